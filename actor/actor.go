@@ -0,0 +1,123 @@
+// Package actor runs goroutines that own their state and are only reached
+// through a mailbox, and stops them all together.
+package actor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrStopped is returned by Send once the system has been stopped.
+var ErrStopped = errors.New("actor: system stopped")
+
+// Mailbox delivers messages to one actor, one at a time.
+type Mailbox[M any] struct {
+	// mu is held for reading by every Send in flight, so closing the
+	// channel waits for them to be delivered.
+	mu     sync.RWMutex
+	ch     chan M
+	closed bool
+}
+
+// Send hands msg to the actor, blocking until it takes it. After the system
+// stopped it returns ErrStopped and the message is dropped.
+func (m *Mailbox[M]) Send(msg M) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.closed {
+		return ErrStopped
+	}
+	m.ch <- msg
+	return nil
+}
+
+func (m *Mailbox[M]) close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.closed {
+		m.closed = true
+		close(m.ch)
+	}
+}
+
+// System owns a set of actors. Stopping it, directly or through its
+// context, refuses new messages, lets every actor handle the ones already
+// being sent and waits until all actor goroutines have returned.
+type System struct {
+	mu       sync.Mutex
+	stopped  bool
+	closers  []func()
+	wg       sync.WaitGroup
+	running  atomic.Int64
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Start creates a system that stops when ctx is done.
+func Start(ctx context.Context) *System {
+	s := &System{done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			s.Stop()
+		case <-s.done:
+		}
+	}()
+	return s
+}
+
+// Spawn starts an actor calling handle for each message sent to the
+// returned mailbox. On a stopped system no goroutine is started and the
+// mailbox refuses every message.
+func Spawn[M any](s *System, handle func(M)) *Mailbox[M] {
+	m := &Mailbox[M]{ch: make(chan M)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		m.closed = true
+		return m
+	}
+	s.closers = append(s.closers, m.close)
+	s.wg.Add(1)
+	s.running.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.running.Add(-1)
+		for msg := range m.ch {
+			handle(msg)
+		}
+	}()
+	return m
+}
+
+// Stop shuts the system down and returns once every actor has exited.
+// Handlers must not block on other actors of the same system, or draining
+// cannot finish.
+func (s *System) Stop() {
+	s.stopOnce.Do(func() {
+		s.mu.Lock()
+		s.stopped = true
+		closers := s.closers
+		s.closers = nil
+		s.mu.Unlock()
+
+		for _, closeMailbox := range closers {
+			closeMailbox()
+		}
+		s.wg.Wait()
+		close(s.done)
+	})
+	<-s.done
+}
+
+// Done is closed once Stop has finished.
+func (s *System) Done() <-chan struct{} {
+	return s.done
+}
+
+// Running is the number of actor goroutines that have not returned yet.
+func (s *System) Running() int {
+	return int(s.running.Load())
+}
@@ -0,0 +1,121 @@
+package actor
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitForGoroutines polls until at most n goroutines are left.
+func waitForGoroutines(t *testing.T, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > n {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("%d goroutines left, want at most %d\n%s", runtime.NumGoroutine(), n, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStopLeavesNoGoroutines(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for run := 0; run < 5; run++ {
+		s := Start(context.Background())
+		counts := make([]int, 100)
+		boxes := make([]*Mailbox[int], len(counts))
+		for i := range boxes {
+			i := i
+			boxes[i] = Spawn(s, func(n int) { counts[i] += n })
+		}
+
+		var wg sync.WaitGroup
+		for i := range boxes {
+			wg.Add(1)
+			go func(box *Mailbox[int]) {
+				defer wg.Done()
+				for j := 0; j < 10; j++ {
+					if err := box.Send(1); err != nil {
+						t.Error(err)
+					}
+				}
+			}(boxes[i])
+		}
+		wg.Wait()
+		s.Stop()
+
+		if s.Running() != 0 {
+			t.Fatalf("run %d: %d actors still running after Stop", run, s.Running())
+		}
+		for i, n := range counts {
+			if n != 10 {
+				t.Fatalf("run %d: actor %d handled %d messages, want 10", run, i, n)
+			}
+		}
+	}
+	waitForGoroutines(t, before)
+}
+
+func TestSendAfterStopFails(t *testing.T) {
+	s := Start(context.Background())
+	box := Spawn(s, func(int) {})
+	s.Stop()
+	if err := box.Send(1); err != ErrStopped {
+		t.Fatalf("Send after Stop = %v, want %v", err, ErrStopped)
+	}
+	late := Spawn(s, func(int) { t.Error("actor spawned on a stopped system ran") })
+	if err := late.Send(1); err != ErrStopped {
+		t.Fatalf("Send to late actor = %v, want %v", err, ErrStopped)
+	}
+}
+
+func TestPendingSendsAreDrained(t *testing.T) {
+	s := Start(context.Background())
+	release := make(chan struct{})
+	var handled sync.WaitGroup
+	handled.Add(3)
+	box := Spawn(s, func(int) {
+		<-release
+		handled.Done()
+	})
+
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		go func() { errs <- box.Send(1) }()
+	}
+	// Let the senders queue up behind the busy actor, then stop.
+	time.Sleep(10 * time.Millisecond)
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	close(release)
+	<-stopped
+	for i := 0; i < 3; i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("queued Send = %v, want it delivered", err)
+		}
+	}
+	handled.Wait()
+}
+
+func TestContextCancelStops(t *testing.T) {
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := Start(ctx)
+	box := Spawn(s, func(int) {})
+	cancel()
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("system still running after its context was cancelled")
+	}
+	if err := box.Send(1); err != ErrStopped {
+		t.Fatalf("Send after cancel = %v, want %v", err, ErrStopped)
+	}
+	waitForGoroutines(t, before)
+}
@@ -0,0 +1,259 @@
+// Package board holds the board, positions and traces shared by the
+// traveler simulations.
+package board
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/simtime"
+)
+
+// Position of a cell. Z is the layer and stays 0 on flat boards.
+type Position struct {
+	X, Y, Z int
+}
+
+// Board is a Width x Height x Depth grid of the given topology with one lock
+// per cell.
+type Board struct {
+	Width, Height, Depth int
+	Topology             Topology
+	// Map, when set, marks cells no one may enter.
+	Map *Map
+	// HoldUnit is how long a move keeps both cells held per unit of
+	// terrain cost. Zero makes moves instant.
+	HoldUnit time.Duration
+	// PrintClocks adds the vector clock of each trace to the output.
+	PrintClocks bool
+	time        simtime.Clock
+	cells       []CellLock
+	// crowded is set once any cell admits more than one holder.
+	crowded bool
+
+	traceMu sync.Mutex
+	hooks   []TraceHook
+	// stamped counts the traces made, to number them.
+	stamped uint64
+}
+
+// TraceHook sees every trace made by NewTrace, in timestamp order.
+type TraceHook func(Trace)
+
+// New creates a flat torus of the given size with the clock started now.
+func New(width, height int) *Board {
+	b, err := NewTopology(Torus, width, height, 1)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// NewTopology creates a board of any topology. Depth is only used by
+// Torus3D and must be 1 otherwise.
+func NewTopology(t Topology, width, height, depth int) (*Board, error) {
+	if width < 1 || height < 1 || depth < 1 {
+		return nil, fmt.Errorf("board %dx%dx%d: all sizes must be positive", width, height, depth)
+	}
+	if depth != 1 && t != Torus3D {
+		return nil, fmt.Errorf("board %dx%dx%d: %s boards are flat", width, height, depth, t)
+	}
+	b := &Board{
+		Width:    width,
+		Height:   height,
+		Depth:    depth,
+		Topology: t,
+		time:     simtime.NewWall(),
+		cells:    make([]CellLock, width*height*depth),
+	}
+	for i := range b.cells {
+		b.cells[i] = newCellLock(1, b.time)
+	}
+	return b, nil
+}
+
+// Cell returns the lock guarding the cell at p.
+func (b *Board) Cell(p Position) *CellLock {
+	return &b.cells[b.index(p)]
+}
+
+func (b *Board) index(p Position) int {
+	return (p.Z*b.Height+p.Y)*b.Width + p.X
+}
+
+// SetCapacity lets k holders share the cell at p. Call it before anyone
+// uses the cell: the lock is replaced, not resized.
+func (b *Board) SetCapacity(p Position, k int) {
+	if k < 1 {
+		panic(fmt.Sprintf("board: capacity %d of cell (%d, %d, %d)", k, p.X, p.Y, p.Z))
+	}
+	b.cells[b.index(p)] = newCellLock(k, b.time)
+	if k > 1 {
+		b.crowded = true
+	}
+}
+
+// Shared reports whether any cell admits more than one holder.
+func (b *Board) Shared() bool {
+	return b.crowded
+}
+
+// Capacity is the number of holders the cell at p admits.
+func (b *Board) Capacity(p Position) int {
+	return b.Cell(p).Capacity()
+}
+
+// Room is how many travelers the board holds at once: the capacity of
+// every cell that is not a wall.
+func (b *Board) Room() int {
+	room := 0
+	for i := range b.cells {
+		p := Position{X: i % b.Width, Y: i / b.Width % b.Height, Z: i / (b.Width * b.Height)}
+		if !b.Wall(p) {
+			room += b.cells[i].Capacity()
+		}
+	}
+	return room
+}
+
+// Directions lists the directions of this board in roll order.
+func (b *Board) Directions() []Direction {
+	return b.Topology.Directions()
+}
+
+// Move returns the position one step from p in direction d. ok is false when
+// the step would leave a board with solid edges, hit a wall, or d does not
+// exist here.
+func (b *Board) Move(p Position, d Direction) (next Position, ok bool) {
+	dx, dy, dz, exists := b.Topology.delta(p, d)
+	if !exists {
+		return p, false
+	}
+	next = Position{X: p.X + dx, Y: p.Y + dy, Z: p.Z + dz}
+	if b.Topology.wrapsX() {
+		next.X = wrap(next.X, b.Width)
+	}
+	if b.Topology.wrapsY() {
+		next.Y = wrap(next.Y, b.Height)
+	}
+	next.Z = wrap(next.Z, b.Depth)
+	if !b.Contains(next) || b.Wall(next) {
+		return p, false
+	}
+	return next, true
+}
+
+// Wall reports whether p is a wall of the board's map.
+func (b *Board) Wall(p Position) bool {
+	return b.Map != nil && b.Map.Wall(p)
+}
+
+// Start returns where the map wants the traveler with symbol to start.
+func (b *Board) Start(symbol rune) (Position, bool) {
+	if b.Map == nil {
+		return Position{}, false
+	}
+	p, ok := b.Map.Starts[symbol]
+	return p, ok
+}
+
+func wrap(v, size int) int {
+	return ((v % size) + size) % size
+}
+
+// Neighbours returns the positions reachable in one step, in roll order.
+func (b *Board) Neighbours(p Position) []Position {
+	var result []Position
+	for _, d := range b.Directions() {
+		if next, ok := b.Move(p, d); ok {
+			result = append(result, next)
+		}
+	}
+	return result
+}
+
+// RandomPosition picks a uniformly random cell, walls included.
+func (b *Board) RandomPosition(r *rand.Rand) Position {
+	return Position{X: r.Intn(b.Width), Y: r.Intn(b.Height), Z: r.Intn(b.Depth)}
+}
+
+// Contains reports whether p lies on the board.
+func (b *Board) Contains(p Position) bool {
+	return p.X >= 0 && p.X < b.Width && p.Y >= 0 && p.Y < b.Height && p.Z >= 0 && p.Z < b.Depth
+}
+
+// Elapsed is the time on the board clock, used for trace stamps.
+func (b *Board) Elapsed() time.Duration {
+	return b.time.Now()
+}
+
+// Time is the clock the board and its travelers run on: it stamps the
+// traces, paces the holds of moves and times out waits for cells. A new
+// board runs on the wall clock, started when the board was created.
+func (b *Board) Time() simtime.Clock {
+	return b.time
+}
+
+// SetTime puts the board on clock c. Call it before anyone uses the board.
+func (b *Board) SetTime(c simtime.Clock) {
+	b.time = c
+	for i := range b.cells {
+		b.cells[i].time = c
+	}
+}
+
+// AddHook registers h for all traces made from now on.
+func (b *Board) AddHook(h TraceHook) {
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+	b.hooks = append(b.hooks, h)
+}
+
+// NewTraces stamps several traces with one timestamp, for moves that happen
+// in a single step, and passes them to the hooks in order.
+func (b *Board) NewTraces(traces ...Trace) []Trace {
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+	stamp := b.Elapsed()
+	for i := range traces {
+		b.stamped++
+		traces[i].TimeStamp = stamp
+		traces[i].seq = b.stamped
+		traces[i].Fill = b.fill(traces[i].Position)
+		for _, hook := range b.hooks {
+			hook(traces[i])
+		}
+	}
+	return traces
+}
+
+// fill is the number of holders of the cell at p, or 0 when every cell of
+// the board is a plain mutex and the count says nothing new.
+func (b *Board) fill(p Position) int {
+	if !b.crowded || !b.Contains(p) {
+		return 0
+	}
+	return b.Cell(p).Held()
+}
+
+// NewTrace stamps a trace and passes it to the hooks. Stamping and hooks run
+// under one lock, so hooks see the traces of all entities in time order.
+func (b *Board) NewTrace(id int, pos Position, symbol rune) Trace {
+	b.traceMu.Lock()
+	defer b.traceMu.Unlock()
+	b.stamped++
+	trace := Trace{
+		TimeStamp: b.Elapsed(),
+		ID:        id,
+		Position:  pos,
+		Symbol:    symbol,
+		Fill:      b.fill(pos),
+		seq:       b.stamped,
+	}
+	for _, hook := range b.hooks {
+		hook(trace)
+	}
+	return trace
+}
@@ -0,0 +1,25 @@
+package board
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoom(t *testing.T) {
+	if room := New(3, 2).Room(); room != 6 {
+		t.Errorf("3x2 board has room for %d, want 6", room)
+	}
+
+	// walls hold no one, a digit lets that many share the cell
+	m, err := ReadMap(strings.NewReader("#.3\n.#A\n\n...\n##.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewMap(Torus3D, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room := b.Room(); room != 10 {
+		t.Errorf("map has room for %d, want 10", room)
+	}
+}
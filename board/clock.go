@@ -0,0 +1,133 @@
+package board
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// VectorClock counts, per entity ID, the events of that entity known to
+// have happened before. Entries past the end are zero, so a clock grows as
+// it meets new IDs.
+type VectorClock []uint64
+
+// ClockLabel marks the annotation "-2 ts CLOCK id v0,v1,..." written after
+// the record it belongs to when clocks are printed.
+const ClockLabel = "CLOCK"
+
+// Tick returns c with one more event of id.
+func (c VectorClock) Tick(id int) VectorClock {
+	if id >= len(c) {
+		c = append(c, make(VectorClock, id+1-len(c))...)
+	}
+	c[id]++
+	return c
+}
+
+// Merge returns c raised to everything o knows.
+func (c VectorClock) Merge(o VectorClock) VectorClock {
+	if len(o) > len(c) {
+		c = append(c, make(VectorClock, len(o)-len(c))...)
+	}
+	for i, v := range o {
+		c[i] = max(c[i], v)
+	}
+	return c
+}
+
+// Copy returns a clock that does not share storage with c.
+func (c VectorClock) Copy() VectorClock {
+	return append(VectorClock(nil), c...)
+}
+
+// Sum is the number of events c knows of. An event that happened before
+// another has a smaller sum, so sorting by Sum orders events causally.
+func (c VectorClock) Sum() uint64 {
+	var sum uint64
+	for _, v := range c {
+		sum += v
+	}
+	return sum
+}
+
+func (c VectorClock) String() string {
+	parts := make([]string, len(c))
+	for i, v := range c {
+		parts[i] = strconv.FormatUint(v, 10)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseVectorClock reads a clock written by String.
+func ParseVectorClock(s string) (VectorClock, error) {
+	var c VectorClock
+	for _, part := range strings.Split(s, ",") {
+		v, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("vector clock %q: %q is not a count", s, part)
+		}
+		c = append(c, v)
+	}
+	return c, nil
+}
+
+// Order is how two events are related by their clocks.
+type Order int
+
+const (
+	Concurrent Order = iota
+	Before
+	After
+	Same
+)
+
+func (o Order) String() string {
+	return [...]string{"CONCURRENT", "BEFORE", "AFTER", "SAME"}[o]
+}
+
+// Compare tells whether the event stamped a happened before, after, at
+// the same time as or concurrently with the one stamped b.
+func Compare(a, b VectorClock) Order {
+	less, greater := false, false
+	for i := 0; i < max(len(a), len(b)); i++ {
+		var x, y uint64
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		less = less || x < y
+		greater = greater || x > y
+	}
+	switch {
+	case less && greater:
+		return Concurrent
+	case less:
+		return Before
+	case greater:
+		return After
+	}
+	return Same
+}
+
+// cellClock is the clock a cell carries from one holder to the next.
+type cellClock struct {
+	mu    sync.Mutex
+	clock VectorClock
+}
+
+// observe returns what the holders that left the cell knew.
+func (c *cellClock) observe() VectorClock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clock.Copy()
+}
+
+// handOver adds what a leaving holder knows.
+func (c *cellClock) handOver(clock VectorClock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock = c.clock.Merge(clock)
+}
@@ -0,0 +1,48 @@
+package board
+
+import "testing"
+
+func TestCellCarriesClockToNextHolder(t *testing.T) {
+	b := New(3, 1)
+	left, middle, right := Position{X: 0}, Position{X: 1}, Position{X: 2}
+	first := b.NewTraveler(0, 'A', left)
+	second := b.NewTraveler(1, 'B', right)
+	b.Cell(left).Lock()
+	b.Cell(right).Lock()
+	first.StoreTrace()
+	second.StoreTrace()
+	if got := Compare(first.Clock, second.Clock); got != Concurrent {
+		t.Fatalf("independent starts are %v, want CONCURRENT", got)
+	}
+
+	// A steps into the middle and back, then B takes the middle.
+	if !first.StepTo(middle, 0) || !first.StepTo(left, 0) {
+		t.Fatal("A cannot move")
+	}
+	leftMiddle := first.Traces[len(first.Traces)-1].Clock
+	if !second.StepTo(middle, 0) {
+		t.Fatal("B cannot take the middle cell")
+	}
+	entered := second.Traces[len(second.Traces)-1].Clock
+	if got := Compare(leftMiddle, entered); got != Before {
+		t.Errorf("A leaving the cell is %v B entering it, want BEFORE", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b VectorClock
+		want Order
+	}{
+		{VectorClock{1, 0}, VectorClock{1, 0}, Same},
+		{VectorClock{1}, VectorClock{1, 0, 0}, Same},
+		{VectorClock{1, 0}, VectorClock{1, 1}, Before},
+		{VectorClock{2, 1}, VectorClock{1}, After},
+		{VectorClock{1, 0}, VectorClock{0, 1}, Concurrent},
+	}
+	for _, tt := range tests {
+		if got := Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
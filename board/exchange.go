@@ -0,0 +1,165 @@
+package board
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/simtime"
+)
+
+// StepResult tells how a step through an Exchange ended.
+type StepResult int
+
+const (
+	Moved StepResult = iota
+	Swapped
+	TimedOut
+)
+
+func (r StepResult) String() string {
+	return [...]string{"MOVED", "SWAPPED", "TIMED_OUT"}[r]
+}
+
+// Exchange lets two adjacent travelers that each hold their own cell and
+// want the other's trade places. A swap takes no extra cell locks: both
+// cells stay held the whole time and only change hands, so it cannot
+// deadlock. Offers are matched under a single mutex and kept by traveler,
+// since on cells that admit several travelers more than one may be waiting
+// at the same cell.
+type Exchange struct {
+	board  *Board
+	mu     sync.Mutex
+	offers map[int]*offer
+}
+
+// offer is a traveler at cell from waiting for the cell to. clock is what
+// it knew when it made the offer, and on acceptance the clock of the swap.
+type offer struct {
+	from     Position
+	to       Position
+	id       int
+	symbol   rune
+	clock    VectorClock
+	trace    Trace
+	accepted chan struct{}
+}
+
+func NewExchange(b *Board) *Exchange {
+	return &Exchange{board: b, offers: make(map[int]*offer)}
+}
+
+// partner is the traveler at to waiting for from, the one with the lowest
+// ID when several are, or nil. The caller holds e.mu.
+func (e *Exchange) partner(from, to Position) *offer {
+	var found *offer
+	for _, o := range e.offers {
+		if o.from == to && o.to == from && (found == nil || o.id < found.id) {
+			found = o
+		}
+	}
+	return found
+}
+
+// Step moves t, which holds its cell, to the neighbouring cell to. It either
+// takes to within timeout and frees the old cell, or swaps with the traveler
+// holding to if that one wants t's cell, or gives up. On Moved and Swapped
+// the new position is traced; both swap traces share one timestamp and,
+// being one event of both travelers, one clock.
+func (e *Exchange) Step(t *Traveler, to Position, timeout time.Duration) StepResult {
+	from := t.Position
+
+	e.mu.Lock()
+	if partner := e.partner(from, to); partner != nil {
+		delete(e.offers, partner.id)
+		t.Clock = t.Clock.Merge(partner.clock).Tick(t.ID).Tick(partner.id)
+		traces := e.board.NewTraces(
+			Trace{ID: t.ID, Position: to, Symbol: t.Symbol, Clock: t.Clock.Copy()},
+			Trace{ID: partner.id, Position: from, Symbol: partner.symbol, Clock: t.Clock.Copy()},
+		)
+		partner.trace = traces[1]
+		partner.clock = t.Clock.Copy()
+		close(partner.accepted)
+		e.mu.Unlock()
+
+		t.Account()
+		t.Position = to
+		t.Traces = append(t.Traces, traces[0])
+		return Swapped
+	}
+	mine := &offer{from: from, to: to, id: t.ID, symbol: t.Symbol, clock: t.Clock.Copy(), accepted: make(chan struct{})}
+	e.offers[t.ID] = mine
+	e.mu.Unlock()
+
+	switch e.await(to, mine, timeout) {
+	case Moved:
+		if !e.withdraw(mine) {
+			// A partner sharing to accepted the offer after t took a
+			// place there too. The swap stands; the extra place goes back.
+			e.board.Cell(to).Unlock()
+			<-mine.accepted
+			break
+		}
+		t.Enter(to)
+		t.Account()
+		t.Position = to
+		t.StoreTrace()
+		e.board.hold([]Position{to})
+		t.Leave(from)
+		e.board.Cell(from).Unlock()
+		return Moved
+	case TimedOut:
+		if e.withdraw(mine) {
+			return TimedOut
+		}
+		// Accepted while the timer fired.
+		<-mine.accepted
+	}
+	t.Account()
+	t.Position = to
+	t.Clock = mine.clock
+	t.Traces = append(t.Traces, mine.trace)
+	return Swapped
+}
+
+// await waits at most timeout for the cell to, which it then holds, or for
+// a partner to accept mine.
+func (e *Exchange) await(to Position, mine *offer, timeout time.Duration) StepResult {
+	cell := e.board.Cell(to)
+	e.board.time.Yield()
+	if simtime.Turns(e.board.time) {
+		result := TimedOut
+		e.board.time.Wait(timeout, func() bool {
+			select {
+			case <-mine.accepted:
+				result = Swapped
+			default:
+				if cell.take() {
+					result = Moved
+				}
+			}
+			return result != TimedOut
+		})
+		return result
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case cell.token <- struct{}{}:
+		return Moved
+	case <-mine.accepted:
+		return Swapped
+	case <-timer.C:
+		return TimedOut
+	}
+}
+
+// withdraw removes o unless it was already accepted.
+func (e *Exchange) withdraw(o *offer) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.offers[o.id] != o {
+		return false
+	}
+	delete(e.offers, o.id)
+	return true
+}
@@ -0,0 +1,47 @@
+package board
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSwapWithOneOfTwoOffersAtASharedCell(t *testing.T) {
+	b := New(2, 1)
+	shared, single := Position{X: 0}, Position{X: 1}
+	b.SetCapacity(shared, 2)
+	e := NewExchange(b)
+
+	var travelers []*Traveler
+	for id, pos := range []Position{shared, shared, single} {
+		b.Cell(pos).Lock()
+		travelers = append(travelers, b.NewTraveler(id, rune('A'+id), pos))
+	}
+	results := make(chan [2]int, 3)
+	step := func(id int, to Position) {
+		results <- [2]int{id, int(e.Step(travelers[id], to, 50*time.Millisecond))}
+	}
+	// both travelers on the shared cell offer to swap for the single one
+	go step(0, single)
+	go step(1, single)
+	time.Sleep(10 * time.Millisecond)
+	go step(2, shared)
+
+	got := make(map[int]StepResult)
+	for range 3 {
+		select {
+		case r := <-results:
+			got[r[0]] = StepResult(r[1])
+		case <-time.After(time.Second):
+			t.Fatalf("steps still running, finished %v", got)
+		}
+	}
+	if got[0] != Swapped || got[1] != TimedOut || got[2] != Swapped {
+		t.Errorf("results %v, want 0 and 2 swapped, 1 timed out", got)
+	}
+	if travelers[0].Position != single || travelers[1].Position != shared || travelers[2].Position != shared {
+		t.Errorf("travelers at %v, %v, %v", travelers[0].Position, travelers[1].Position, travelers[2].Position)
+	}
+	if held := b.Cell(shared).Held(); held != 2 {
+		t.Errorf("shared cell held by %d", held)
+	}
+}
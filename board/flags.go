@@ -0,0 +1,76 @@
+package board
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dawid831/ParallelProgramming/simtime"
+)
+
+// Options are the -topology, -depth, -map, -capacity and -clocks command
+// line flags, with the clock flags of simtime.
+type Options struct {
+	Topology string
+	Depth    int
+	MapPath  string
+	Capacity int
+	Clocks   bool
+	Time     *simtime.Options
+}
+
+// RegisterFlags adds the board flags to the default flag set.
+func RegisterFlags() *Options {
+	o := &Options{}
+	flag.StringVar(&o.Topology, "topology", "torus", "board topology: torus, bounded, cylinder, hex or torus3d")
+	flag.IntVar(&o.Depth, "depth", 1, "number of layers of a torus3d board")
+	flag.StringVar(&o.MapPath, "map", "", "text map of walls ('#'), road ('.'), grass (','), swamp ('~'), shared road ('1'-'9') and traveler starts ('A'-'Z'); sets the board size")
+	flag.IntVar(&o.Capacity, "capacity", 1, "travelers a cell admits at once, unless the map gives the cell its own")
+	flag.BoolVar(&o.Clocks, "clocks", false, "write the vector clock of every trace as a CLOCK annotation")
+	o.Time = simtime.RegisterFlags()
+	return o
+}
+
+// New creates the board chosen on the command line. A map overrides the
+// given size.
+func (o *Options) New(width, height int) (*Board, error) {
+	t, err := ParseTopology(o.Topology)
+	if err != nil {
+		return nil, err
+	}
+	if o.Capacity < 1 {
+		return nil, fmt.Errorf("capacity %d: cells must admit at least one traveler", o.Capacity)
+	}
+	var b *Board
+	if o.MapPath != "" {
+		var m *Map
+		if m, err = LoadMap(o.MapPath); err != nil {
+			return nil, err
+		}
+		b, err = NewMap(t, m)
+	} else {
+		b, err = NewTopology(t, width, height, o.Depth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.PrintClocks = o.Clocks
+	clock, err := o.Time.New()
+	if err != nil {
+		return nil, err
+	}
+	b.SetTime(clock)
+	if o.Capacity == 1 {
+		return b, nil
+	}
+	for z := 0; z < b.Depth; z++ {
+		for y := 0; y < b.Height; y++ {
+			for x := 0; x < b.Width; x++ {
+				p := Position{X: x, Y: y, Z: z}
+				if b.Map == nil || b.Map.Capacity(p) == 0 {
+					b.SetCapacity(p, o.Capacity)
+				}
+			}
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,114 @@
+package board
+
+import (
+	"context"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/simtime"
+)
+
+// CellLock is a counting semaphore with room for capacity holders that can
+// give up; with capacity 1 it is a mutex. A failed or cancelled attempt
+// leaves no goroutine behind that could take the lock later.
+//
+// Admission is FIFO: a holder leaving a full cell hands its slot straight
+// to the longest waiting sender on the channel, so a newcomer cannot slip
+// past travelers already queued for the cell.
+//
+// The cell also carries the vector clock of its holders forward: whoever
+// takes it next learns what everyone who left it knew.
+//
+// Waits go through the clock of the board. On a virtual clock a waiting
+// task looks at the cell between the turns of the others, the first to
+// start waiting looking first. Every attempt and every release is a Yield
+// point of the clock.
+type CellLock struct {
+	token chan struct{}
+	clock *cellClock
+	time  simtime.Clock
+}
+
+func newCellLock(capacity int, c simtime.Clock) CellLock {
+	return CellLock{token: make(chan struct{}, capacity), clock: &cellClock{}, time: c}
+}
+
+// Lock blocks until the cell has room and takes a slot.
+func (l *CellLock) Lock() {
+	simtime.Send(l.time, l.token, struct{}{}, -1)
+}
+
+// TryLock takes a slot only if one is free right now.
+func (l *CellLock) TryLock() bool {
+	l.time.Yield()
+	return l.take()
+}
+
+// take is TryLock without the Yield, for use inside a Wait.
+func (l *CellLock) take() bool {
+	select {
+	case l.token <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// LockContext waits for the cell until ctx is done.
+func (l *CellLock) LockContext(ctx context.Context) error {
+	l.time.Yield()
+	if simtime.Turns(l.time) {
+		locked := false
+		l.time.Wait(-1, func() bool {
+			if ctx.Err() != nil {
+				return true
+			}
+			locked = l.take()
+			return locked
+		})
+		if locked {
+			return nil
+		}
+		return ctx.Err()
+	}
+	select {
+	case l.token <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LockTimeout waits for the cell at most timeout.
+func (l *CellLock) LockTimeout(timeout time.Duration) bool {
+	return simtime.Send(l.time, l.token, struct{}{}, timeout)
+}
+
+// Unlock frees one slot. Unlocking an empty cell is a bug, as with sync.Mutex.
+func (l *CellLock) Unlock() {
+	l.time.Yield()
+	select {
+	case <-l.token:
+	default:
+		panic("board: unlock of unlocked cell")
+	}
+}
+
+// Locked reports whether anyone holds the cell at the moment of the call.
+func (l *CellLock) Locked() bool {
+	return len(l.token) > 0
+}
+
+// Full reports whether every slot is taken at the moment of the call.
+func (l *CellLock) Full() bool {
+	return len(l.token) == cap(l.token)
+}
+
+// Held is the number of slots taken at the moment of the call.
+func (l *CellLock) Held() int {
+	return len(l.token)
+}
+
+// Capacity is the number of holders the cell admits.
+func (l *CellLock) Capacity() int {
+	return cap(l.token)
+}
@@ -0,0 +1,114 @@
+package board
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockTimeoutLeavesCellFree(t *testing.T) {
+	b := New(3, 3)
+	cell := b.Cell(Position{X: 1, Y: 1})
+	cell.Lock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if cell.LockTimeout(time.Millisecond) {
+				t.Error("took a held cell")
+			}
+		}()
+	}
+	wg.Wait()
+	cell.Unlock()
+
+	// The old tryLock left goroutines that grabbed the cell after their
+	// caller had given up. Give any such stragglers time to show up.
+	time.Sleep(10 * time.Millisecond)
+	if cell.Locked() {
+		t.Fatal("cell still locked after every attempt timed out")
+	}
+	if !cell.TryLock() {
+		t.Fatal("cell cannot be taken after timeouts")
+	}
+	cell.Unlock()
+}
+
+func TestLockContextCancel(t *testing.T) {
+	b := New(2, 2)
+	cell := b.Cell(Position{})
+	cell.Lock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		errs <- cell.LockContext(ctx)
+	}()
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("LockContext = %v, want %v", err, context.Canceled)
+	}
+
+	cell.Unlock()
+	if err := cell.LockContext(context.Background()); err != nil {
+		t.Fatalf("LockContext on free cell = %v", err)
+	}
+}
+
+func TestNoCellStaysLockedUnderContention(t *testing.T) {
+	b := New(2, 1)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for step := 0; step < 100; step++ {
+				cell := b.Cell(Position{X: (i + step) % 2})
+				if cell.LockTimeout(50 * time.Microsecond) {
+					cell.Unlock()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for x := 0; x < b.Width; x++ {
+		if b.Cell(Position{X: x}).Locked() {
+			t.Errorf("cell (%d, 0) locked after all travelers finished", x)
+		}
+	}
+}
+
+func TestCapacityAdmitsInArrivalOrder(t *testing.T) {
+	b := New(1, 1)
+	b.SetCapacity(Position{}, 2)
+	cell := b.Cell(Position{})
+	if !cell.TryLock() || !cell.TryLock() {
+		t.Fatal("cell of capacity 2 refused a second holder")
+	}
+	if cell.LockTimeout(time.Millisecond) {
+		t.Fatal("full cell admitted a third holder")
+	}
+
+	admitted := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			cell.Lock()
+			admitted <- i
+		}(i)
+		// Let waiter i queue up before the next one arrives.
+		time.Sleep(5 * time.Millisecond)
+	}
+	for want := 0; want < 3; want++ {
+		cell.Unlock()
+		if got := <-admitted; got != want {
+			t.Fatalf("waiter %d admitted, want %d", got, want)
+		}
+	}
+	if cell.Held() != 2 {
+		t.Fatalf("Held = %d, want 2", cell.Held())
+	}
+}
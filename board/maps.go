@@ -0,0 +1,140 @@
+package board
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Map is a board layout read from text: '#' is a wall, '.' is road, ','
+// is grass, '~' is swamp, a digit k from 1 to 9 is road that admits k
+// travelers at once and an upper case letter is road where the traveler
+// with that symbol starts.
+// Layers of a 3D map are separated by an empty line.
+type Map struct {
+	Width, Height, Depth int
+	Starts               map[rune]Position
+	walls                map[Position]bool
+	capacity             map[Position]int
+	terrain              map[Position]Terrain
+}
+
+// ReadMap parses a map. All rows and layers must have the same size.
+func ReadMap(r io.Reader) (*Map, error) {
+	m := &Map{
+		Starts:   make(map[rune]Position),
+		walls:    make(map[Position]bool),
+		capacity: make(map[Position]int),
+		terrain:  make(map[Position]Terrain),
+	}
+	scanner := bufio.NewScanner(r)
+	lineNr, y, z := 0, 0, 0
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" {
+			if y > 0 {
+				if m.Height == 0 {
+					m.Height = y
+				} else if y != m.Height {
+					return nil, fmt.Errorf("map line %d: layer %d has %d rows, want %d", lineNr, z, y, m.Height)
+				}
+				y = 0
+				z++
+			}
+			continue
+		}
+
+		row := []rune(line)
+		if m.Width == 0 {
+			m.Width = len(row)
+		} else if len(row) != m.Width {
+			return nil, fmt.Errorf("map line %d: row has %d cells, want %d", lineNr, len(row), m.Width)
+		}
+		for x, c := range row {
+			p := Position{X: x, Y: y, Z: z}
+			switch {
+			case c == '#':
+				m.walls[p] = true
+			case c == '.':
+			case c == ',':
+				m.terrain[p] = Grass
+			case c == '~':
+				m.terrain[p] = Swamp
+			case c >= '1' && c <= '9':
+				m.capacity[p] = int(c - '0')
+			case unicode.IsUpper(c):
+				if first, seen := m.Starts[c]; seen {
+					return nil, fmt.Errorf("map line %d: second start of %c, first at (%d, %d, %d)", lineNr, c, first.X, first.Y, first.Z)
+				}
+				m.Starts[c] = p
+			default:
+				return nil, fmt.Errorf("map line %d: unknown cell %q at column %d", lineNr, c, x+1)
+			}
+		}
+		y++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if y > 0 {
+		if m.Height == 0 {
+			m.Height = y
+		} else if y != m.Height {
+			return nil, fmt.Errorf("map: layer %d has %d rows, want %d", z, y, m.Height)
+		}
+		z++
+	}
+	if z == 0 {
+		return nil, fmt.Errorf("map is empty")
+	}
+	m.Depth = z
+	return m, nil
+}
+
+// LoadMap reads the map file at path.
+func LoadMap(path string) (*Map, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	m, err := ReadMap(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return m, nil
+}
+
+// Wall reports whether p is a wall on the map.
+func (m *Map) Wall(p Position) bool {
+	return m.walls[p]
+}
+
+// Terrain is the ground at p; road unless the map says otherwise.
+func (m *Map) Terrain(p Position) Terrain {
+	return m.terrain[p]
+}
+
+// Capacity is the number of travelers the map lets share p, or 0 when the
+// map does not say.
+func (m *Map) Capacity(p Position) int {
+	return m.capacity[p]
+}
+
+// NewMap creates a board of topology t shaped by m.
+func NewMap(t Topology, m *Map) (*Board, error) {
+	b, err := NewTopology(t, m.Width, m.Height, m.Depth)
+	if err != nil {
+		return nil, err
+	}
+	b.Map = m
+	for p, k := range m.capacity {
+		b.SetCapacity(p, k)
+	}
+	return b, nil
+}
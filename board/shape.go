@@ -0,0 +1,139 @@
+package board
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Shape lists the cells of a traveler as offsets from its anchor, the
+// cell it is steered by. Offsets grow right and down and are walked cell
+// by cell, so a shape wraps and hits walls exactly like a single step.
+type Shape []Position
+
+var (
+	Single = Shape{{0, 0, 0}}
+	Domino = Shape{{0, 0, 0}, {1, 0, 0}}
+	Block  = Shape{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}, {1, 1, 0}}
+	LShape = Shape{{0, 0, 0}, {0, 1, 0}, {0, 2, 0}, {1, 2, 0}}
+)
+
+var shapeNames = map[string]Shape{
+	"single": Single,
+	"domino": Domino,
+	"block":  Block,
+	"l":      LShape,
+}
+
+// ParseShape accepts single, domino, block and l.
+func ParseShape(s string) (Shape, error) {
+	if shape, ok := shapeNames[strings.ToLower(s)]; ok {
+		return shape, nil
+	}
+	return nil, fmt.Errorf("unknown shape %q", s)
+}
+
+// String is the name ParseShape accepts for s, "custom" for shapes made up
+// elsewhere.
+func (s Shape) String() string {
+	if len(s) == 0 {
+		return "single"
+	}
+	for name, shape := range shapeNames {
+		if slices.Equal(s, shape) {
+			return name
+		}
+	}
+	return "custom"
+}
+
+// Fits checks that s can be laid on b at all: a hex board has no right
+// angles to lay offsets along, and a shape longer than the board either
+// sticks out past a solid edge or, wrapping around, covers one cell twice.
+func (b *Board) Fits(s Shape) error {
+	if b.Topology == Hex && len(s) > 1 {
+		return fmt.Errorf("shape %s: hex boards take single cell travelers only", s)
+	}
+	for _, off := range s {
+		if off.X >= b.Width || off.Y >= b.Height {
+			return fmt.Errorf("shape %s does not fit a %dx%d board", s, b.Width, b.Height)
+		}
+	}
+	return nil
+}
+
+// Cover lists the cells shape s takes with its anchor at p. It fails when
+// a cell lies past a solid edge or on a wall, when the shape wraps onto
+// itself, and on hex boards; see Fits.
+func (b *Board) Cover(p Position, s Shape) ([]Position, bool) {
+	if len(s) == 0 {
+		s = Single
+	}
+	if b.Topology == Hex && len(s) > 1 {
+		return nil, false
+	}
+	cells := make([]Position, 0, len(s))
+	for _, off := range s {
+		q, ok := p, b.Contains(p) && !b.Wall(p)
+		for i := 0; ok && i < off.X; i++ {
+			q, ok = b.Move(q, Right)
+		}
+		for i := 0; ok && i < off.Y; i++ {
+			q, ok = b.Move(q, Down)
+		}
+		if !ok || slices.Contains(cells, q) {
+			return nil, false
+		}
+		cells = append(cells, q)
+	}
+	return cells, true
+}
+
+// LockAll locks every cell in cells or none of them. Cells are taken in
+// board index order, the same order for every caller, so two calls never
+// wait for each other over the cells they lock. That alone does not rule
+// out waits in a ring: StepTo keeps the cells a traveler stands on while
+// it locks the ones it steps to, and a neighbour may wait for those while
+// holding what the traveler wants. The timeout ends such a wait; it covers
+// the whole set, and on expiry the cells taken so far are released again.
+func (b *Board) LockAll(cells []Position, timeout time.Duration) bool {
+	order := append([]Position(nil), cells...)
+	sort.Slice(order, func(i, j int) bool { return b.index(order[i]) < b.index(order[j]) })
+	deadline := b.time.Now() + timeout
+	for i, p := range order {
+		if !b.Cell(p).LockTimeout(deadline - b.time.Now()) {
+			for _, q := range order[:i] {
+				b.Cell(q).Unlock()
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// UnlockAll unlocks every cell in cells.
+func (b *Board) UnlockAll(cells []Position) {
+	for _, p := range cells {
+		b.Cell(p).Unlock()
+	}
+}
+
+// minus returns the cells of a that are not in b.
+func minus(a, b []Position) []Position {
+	var rest []Position
+	for _, p := range a {
+		found := false
+		for _, q := range b {
+			if p == q {
+				found = true
+				break
+			}
+		}
+		if !found {
+			rest = append(rest, p)
+		}
+	}
+	return rest
+}
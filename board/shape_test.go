@@ -0,0 +1,38 @@
+package board
+
+import "testing"
+
+func TestFits(t *testing.T) {
+	tests := []struct {
+		topology      Topology
+		width, height int
+		shape         Shape
+		fits          bool
+	}{
+		{Torus, 4, 4, LShape, true},
+		{Torus, 4, 2, LShape, false}, // wraps onto its own anchor
+		{Bounded, 1, 4, Domino, false},
+		{Cylinder, 2, 2, Block, true},
+		{Hex, 8, 8, Single, true},
+		{Hex, 8, 8, Domino, false},
+	}
+	for _, tt := range tests {
+		b, err := NewTopology(tt.topology, tt.width, tt.height, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Fits(tt.shape); (err == nil) != tt.fits {
+			t.Errorf("%s on %dx%d %s: %v", tt.shape, tt.width, tt.height, tt.topology, err)
+		}
+	}
+}
+
+func TestCoverRejectsShapesCoveringACellTwice(t *testing.T) {
+	b := New(4, 2)
+	if cells, ok := b.Cover(Position{X: 1, Y: 0}, LShape); ok {
+		t.Errorf("L on a 4x2 torus covers %v", cells)
+	}
+	if cells, ok := b.Cover(Position{X: 3, Y: 1}, Block); !ok || len(cells) != 4 {
+		t.Errorf("block wrapping the corner covers %v, %v", cells, ok)
+	}
+}
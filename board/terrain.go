@@ -0,0 +1,78 @@
+package board
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Terrain is the ground of a cell. It sets how costly the cell is to enter:
+// how long a move onto it keeps both cells held and how far it counts when
+// routing.
+type Terrain int
+
+const (
+	Road Terrain = iota
+	Grass
+	Swamp
+	nrOfTerrains
+)
+
+var terrainNames = [...]string{"ROAD", "GRASS", "SWAMP"}
+
+// terrainCosts are multiples of the board's HoldUnit.
+var terrainCosts = [...]int{1, 2, 4}
+
+func (t Terrain) String() string {
+	return terrainNames[t]
+}
+
+// Cost is the price of entering a cell of this terrain.
+func (t Terrain) Cost() int {
+	return terrainCosts[t]
+}
+
+// Terrain is the ground at p. Cells the map says nothing about are road.
+func (b *Board) Terrain(p Position) Terrain {
+	if b.Map == nil {
+		return Road
+	}
+	return b.Map.Terrain(p)
+}
+
+// Weighted reports whether the map lays any terrain other than road.
+func (b *Board) Weighted() bool {
+	return b.Map != nil && len(b.Map.terrain) > 0
+}
+
+// hold keeps the cells of a move held for the cost of the dearest newly
+// entered cell, in units of HoldUnit.
+func (b *Board) hold(entered []Position) {
+	if b.HoldUnit <= 0 {
+		return
+	}
+	cost := 0
+	for _, p := range entered {
+		if c := b.Terrain(p).Cost(); c > cost {
+			cost = c
+		}
+	}
+	b.time.Sleep(time.Duration(cost) * b.HoldUnit)
+}
+
+// TerrainTimes is time spent per terrain.
+type TerrainTimes [nrOfTerrains]time.Duration
+
+// Add sums the times of other into tt.
+func (tt *TerrainTimes) Add(other TerrainTimes) {
+	for i, d := range other {
+		tt[i] += d
+	}
+}
+
+// Print writes one "TERRAIN seconds" line per terrain.
+func (tt TerrainTimes) Print(w io.Writer) {
+	for i, d := range tt {
+		fmt.Fprintf(w, "%-5s %.9f\n", Terrain(i), d.Seconds())
+	}
+}
@@ -0,0 +1,150 @@
+package board
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Topology decides which cells are neighbours and how edges behave.
+type Topology int
+
+const (
+	// Torus wraps around both edges, as the original simulations did.
+	Torus Topology = iota
+	// Bounded has solid edges.
+	Bounded
+	// Cylinder wraps around horizontally only.
+	Cylinder
+	// Hex is a grid of hexagons in "odd-r" offset layout: odd rows are
+	// shifted half a cell to the right, every cell has six neighbours. Rows
+	// wrap around horizontally; top and bottom are solid, so any height
+	// works.
+	Hex
+	// Torus3D stacks Depth torus layers, wrapping between the first and the
+	// last one.
+	Torus3D
+)
+
+var topologyNames = [...]string{"TORUS", "BOUNDED", "CYLINDER", "HEX", "TORUS3D"}
+
+func (t Topology) String() string {
+	return topologyNames[t]
+}
+
+// ParseTopology reads a topology name, in any case.
+func ParseTopology(s string) (Topology, error) {
+	for i, name := range topologyNames {
+		if name == strings.ToUpper(s) {
+			return Topology(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown topology %q", s)
+}
+
+// Direction of a single step. The first four are numbered the same way the
+// simulations have always rolled them.
+type Direction int
+
+const (
+	Up Direction = iota
+	Down
+	Left
+	Right
+	UpLeft
+	UpRight
+	DownLeft
+	DownRight
+	Above
+	Below
+)
+
+var directionNames = [...]string{"UP", "DOWN", "LEFT", "RIGHT", "UP_LEFT", "UP_RIGHT", "DOWN_LEFT", "DOWN_RIGHT", "ABOVE", "BELOW"}
+
+func (d Direction) String() string {
+	return directionNames[d]
+}
+
+// ParseDirection reads a direction name, in any case.
+func ParseDirection(s string) (Direction, error) {
+	for i, name := range directionNames {
+		if name == strings.ToUpper(s) {
+			return Direction(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown direction %q", s)
+}
+
+// Directions lists the directions of a flat square board in roll order.
+var Directions = []Direction{Up, Down, Left, Right}
+
+var (
+	hexDirections  = []Direction{UpLeft, UpRight, Left, Right, DownLeft, DownRight}
+	cubeDirections = []Direction{Up, Down, Left, Right, Above, Below}
+	squareRing     = []Direction{Up, Right, Down, Left}
+	hexRing        = []Direction{UpRight, Right, DownRight, DownLeft, Left, UpLeft}
+)
+
+// Directions lists the directions of t in roll order.
+func (t Topology) Directions() []Direction {
+	switch t {
+	case Hex:
+		return hexDirections
+	case Torus3D:
+		return cubeDirections
+	default:
+		return Directions
+	}
+}
+
+// Ring lists the directions within one layer clockwise, starting up.
+func (t Topology) Ring() []Direction {
+	if t == Hex {
+		return hexRing
+	}
+	return squareRing
+}
+
+func (t Topology) wrapsX() bool {
+	return t != Bounded
+}
+
+func (t Topology) wrapsY() bool {
+	return t == Torus || t == Torus3D
+}
+
+// delta is the offset of one step; on hex boards it depends on the row.
+func (t Topology) delta(p Position, d Direction) (dx, dy, dz int, ok bool) {
+	if t == Hex {
+		shift := p.Y & 1
+		switch d {
+		case Left:
+			return -1, 0, 0, true
+		case Right:
+			return 1, 0, 0, true
+		case UpLeft:
+			return shift - 1, -1, 0, true
+		case UpRight:
+			return shift, -1, 0, true
+		case DownLeft:
+			return shift - 1, 1, 0, true
+		case DownRight:
+			return shift, 1, 0, true
+		}
+		return 0, 0, 0, false
+	}
+	switch d {
+	case Up:
+		return 0, -1, 0, true
+	case Down:
+		return 0, 1, 0, true
+	case Left:
+		return -1, 0, 0, true
+	case Right:
+		return 1, 0, 0, true
+	case Above:
+		return 0, 0, -1, t == Torus3D
+	case Below:
+		return 0, 0, 1, t == Torus3D
+	}
+	return 0, 0, 0, false
+}
@@ -0,0 +1,91 @@
+package board
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+)
+
+type Trace struct {
+	TimeStamp time.Duration
+	ID        int
+	Position  Position
+	Symbol    rune
+	// Fill is how many holders the cell had when the trace was made. It is
+	// only counted on boards with cells of capacity above one.
+	Fill int
+	// Clock orders the trace causally against traces of other entities.
+	// It is nil for entities that keep no clock.
+	Clock VectorClock
+	// seq numbers the traces of a board in the order they were stamped.
+	seq uint64
+}
+
+// SortTraces puts traces gathered from several places, like the cells of
+// the lista2 boards, back in the order they were stamped, so that no
+// entity goes back in time.
+func SortTraces(traces []Trace) {
+	slices.SortStableFunc(traces, func(a, b Trace) int {
+		if c := cmp.Compare(a.TimeStamp, b.TimeStamp); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.seq, b.seq)
+	})
+}
+
+// FillLabel marks the annotation "-2 ts FILL x y fill capacity" written
+// after a record on a cell that admits more than one holder.
+const FillLabel = "FILL"
+
+// Encode maps p to the x y pair written in traces. Layers of a 3D board are
+// laid side by side, so layer z starts at column z*Width. The (-1, -1) exit
+// position is kept as it is.
+func (b *Board) Encode(p Position) (x, y int) {
+	if p.X == -1 && p.Y == -1 {
+		return -1, -1
+	}
+	return p.Z*b.Width + p.X, p.Y
+}
+
+// Decode is the inverse of Encode.
+func (b *Board) Decode(x, y int) Position {
+	if x == -1 && y == -1 {
+		return Position{X: -1, Y: -1}
+	}
+	return Position{X: x % b.Width, Y: y, Z: x / b.Width}
+}
+
+// PrintParameters writes the "-1 N W H TOPOLOGY= T;" line the display script
+// expects. W covers all layers side by side; 3D boards add "DEPTH= D;".
+func (b *Board) PrintParameters(w io.Writer, nrOfEntities int) {
+	fmt.Fprintf(w, "-1 %d %d %d TOPOLOGY= %s;", nrOfEntities, b.Width*b.Depth, b.Height, b.Topology)
+	if b.Topology == Torus3D {
+		fmt.Fprintf(w, "DEPTH= %d;", b.Depth)
+	}
+	fmt.Fprintln(w)
+}
+
+// PrintTraces writes one "timestamp id x y symbol" line per trace. Traces
+// on shared cells are followed by a FILL annotation with the fill level,
+// and with PrintClocks set traces with a clock by a CLOCK annotation.
+func (b *Board) PrintTraces(w io.Writer, traces []Trace) {
+	for _, trace := range traces {
+		x, y := b.Encode(trace.Position)
+		fmt.Fprintf(w, "%.9f %d %d %d %c\n",
+			trace.TimeStamp.Seconds(),
+			trace.ID,
+			x,
+			y,
+			trace.Symbol)
+		if trace.Fill > 0 && b.Capacity(trace.Position) > 1 {
+			fmt.Fprintf(w, "-2 %.9f %s %d %d %d %d\n",
+				trace.TimeStamp.Seconds(), FillLabel, x, y, trace.Fill, b.Capacity(trace.Position))
+		}
+		if b.PrintClocks && len(trace.Clock) > 0 {
+			fmt.Fprintf(w, "-2 %.9f %s %d %s\n",
+				trace.TimeStamp.Seconds(), ClockLabel, trace.ID, trace.Clock)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package board
+
+import (
+	"time"
+	"unicode"
+)
+
+type Traveler struct {
+	ID        int
+	Symbol    rune
+	Position  Position
+	Direction Direction
+	Shape     Shape
+	Traces    []Trace
+	// Spent is the time spent on each terrain, counted up to the last move
+	// or Account.
+	Spent TerrainTimes
+	// Clock ticks with every trace and learns from the cells t enters
+	// what their earlier holders knew.
+	Clock   VectorClock
+	arrived time.Duration
+	board   *Board
+}
+
+// NewTraveler creates a traveler standing at pos on b.
+func (b *Board) NewTraveler(id int, symbol rune, pos Position) *Traveler {
+	return &Traveler{
+		ID:       id,
+		Symbol:   symbol,
+		Position: pos,
+		arrived:  b.Elapsed(),
+		board:    b,
+	}
+}
+
+func (t *Traveler) Board() *Board {
+	return t.board
+}
+
+// Move takes one step in direction d, unless an edge is in the way.
+func (t *Traveler) Move(d Direction) bool {
+	next, ok := t.board.Move(t.Position, d)
+	t.Position = next
+	return ok
+}
+
+// Freeze switches the symbol to lower case, marking a traveler that gave up.
+func (t *Traveler) Freeze() {
+	t.Symbol = unicode.ToLower(t.Symbol)
+}
+
+// Cells lists the cells t covers, its anchor first.
+func (t *Traveler) Cells() []Position {
+	if len(t.Shape) <= 1 {
+		return []Position{t.Position}
+	}
+	cells, _ := t.board.Cover(t.Position, t.Shape)
+	return cells
+}
+
+// StepTo moves the anchor of t to the neighbouring cell to. The cells the
+// shape newly covers are locked together within timeout and the trace is
+// stored. Both old and new cells stay held for the terrain cost of the
+// move, and only then are the cells left behind unlocked. On failure t
+// stays where it was and holds what it held.
+func (t *Traveler) StepTo(to Position, timeout time.Duration) bool {
+	from := t.Cells()
+	cells, ok := t.board.Cover(to, t.Shape)
+	entered := minus(cells, from)
+	if !ok || !t.board.LockAll(entered, timeout) {
+		return false
+	}
+	t.Enter(entered...)
+	t.Account()
+	t.Position = to
+	t.StoreTrace()
+	t.board.hold(entered)
+	left := minus(from, cells)
+	t.Leave(left...)
+	t.board.UnlockAll(left)
+	return true
+}
+
+// Enter merges into the clock of t what the earlier holders of cells knew.
+// Call it once the cells are locked.
+func (t *Traveler) Enter(cells ...Position) {
+	for _, p := range cells {
+		t.Clock = t.Clock.Merge(t.board.Cell(p).clock.observe())
+	}
+}
+
+// Leave hands the clock of t to cells for their next holders. Call it
+// before the cells are unlocked.
+func (t *Traveler) Leave(cells ...Position) {
+	for _, p := range cells {
+		t.board.Cell(p).clock.handOver(t.Clock)
+	}
+}
+
+// Account adds the time since the last move to the terrain under the
+// anchor of t.
+func (t *Traveler) Account() {
+	now := t.board.Elapsed()
+	t.Spent[t.board.Terrain(t.Position)] += now - t.arrived
+	t.arrived = now
+}
+
+// StoreTrace records the current position and symbol as the next event
+// of t. A traveler of several cells stores one trace per cell, all with
+// the same timestamp and clock.
+func (t *Traveler) StoreTrace() {
+	t.Clock = t.Clock.Tick(t.ID)
+	cells := t.Cells()
+	traces := make([]Trace, len(cells))
+	for i, p := range cells {
+		traces[i] = Trace{ID: t.ID, Position: p, Symbol: t.Symbol, Clock: t.Clock.Copy()}
+	}
+	t.Traces = append(t.Traces, t.board.NewTraces(traces...)...)
+}
@@ -0,0 +1,423 @@
+// Package checker rebuilds board occupancy from a trace stream and reports
+// every moment two entities share a cell in a way they must not.
+package checker
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/tracefmt"
+)
+
+// Kind is what an entity is, read from its trace symbol the same way the
+// display script does.
+type Kind int
+
+const (
+	Traveler Kind = iota
+	WildTenant
+	Trap
+	// Caught marks a wild tenant that stepped on a trap ('*').
+	Caught
+)
+
+func (k Kind) String() string {
+	return [...]string{"TRAVELER", "WILD_TENANT", "TRAP", "CAUGHT"}[k]
+}
+
+// KindOf classifies a trace symbol.
+func KindOf(symbol rune) Kind {
+	switch {
+	case symbol == '#':
+		return Trap
+	case symbol == '*':
+		return Caught
+	case unicode.IsDigit(symbol):
+		return WildTenant
+	default:
+		return Traveler
+	}
+}
+
+// Occupant is an entity standing on a cell since a given time.
+type Occupant struct {
+	ID     int
+	Symbol rune
+	Since  time.Duration
+}
+
+func (o Occupant) Kind() Kind {
+	return KindOf(o.Symbol)
+}
+
+// DefaultAllowed lets anything share a cell with a trap, since stepping on
+// one is how an entity gets caught, and nothing else share a cell at all.
+func DefaultAllowed(a, b Occupant) bool {
+	for _, o := range []Occupant{a, b} {
+		if o.Kind() == Trap || o.Kind() == Caught {
+			return true
+		}
+	}
+	return false
+}
+
+// Violation is one entity arriving on a cell it must not share.
+type Violation struct {
+	TimeStamp time.Duration
+	Position  board.Position
+	Occupants []Occupant
+}
+
+func (v Violation) Error() string {
+	names := make([]string, len(v.Occupants))
+	for i, o := range v.Occupants {
+		names[i] = fmt.Sprintf("%d (%c since %.9f)", o.ID, o.Symbol, o.Since.Seconds())
+	}
+	return fmt.Sprintf("%.9f cell (%d, %d) shared by %s",
+		v.TimeStamp.Seconds(), v.Position.X, v.Position.Y, strings.Join(names, ", "))
+}
+
+// Checker consumes traces in timestamp order. Traces with equal timestamps
+// are applied together before checking, so two entities swapping cells in
+// one step are not reported. Several traces of one ID with one timestamp
+// are a multi-cell traveler covering all of those cells; on a virtual clock
+// they can also be one cell traced twice in an instant, the last symbol
+// counting.
+type Checker struct {
+	Allowed func(a, b Occupant) bool
+	// Capacity, when set, lets up to that many entities share a cell
+	// whatever Allowed says. Nil means every cell holds one.
+	Capacity func(board.Position) int
+
+	occupants map[int]Occupant
+	positions map[int][]board.Position
+	cells     map[board.Position][]int
+	batch     []board.Trace
+	last      time.Duration
+}
+
+// New returns a checker using DefaultAllowed.
+func New() *Checker {
+	return &Checker{
+		Allowed:   DefaultAllowed,
+		occupants: make(map[int]Occupant),
+		positions: make(map[int][]board.Position),
+		cells:     make(map[board.Position][]int),
+	}
+}
+
+// Observe takes the next trace and returns violations found in the
+// previous timestamp, which is complete once a later one arrives.
+func (c *Checker) Observe(t board.Trace) []Violation {
+	if t.TimeStamp < c.last {
+		panic(fmt.Sprintf("checker: trace of %d at %.9f older than %.9f", t.ID, t.TimeStamp.Seconds(), c.last.Seconds()))
+	}
+	var violations []Violation
+	if len(c.batch) > 0 && t.TimeStamp > c.last {
+		violations = c.Flush()
+	}
+	c.last = t.TimeStamp
+	c.batch = append(c.batch, t)
+	return violations
+}
+
+// Flush checks the traces still waiting for a later timestamp.
+func (c *Checker) Flush() []Violation {
+	batch := c.batch
+	c.batch = nil
+
+	var ids []int
+	covers := make(map[int][]board.Position)
+	symbols := make(map[int]rune)
+	for _, t := range batch {
+		if _, seen := covers[t.ID]; !seen {
+			ids = append(ids, t.ID)
+			covers[t.ID] = nil
+		}
+		symbols[t.ID] = t.Symbol
+		if t.Position.X == -1 && t.Position.Y == -1 || slices.Contains(covers[t.ID], t.Position) {
+			continue
+		}
+		covers[t.ID] = append(covers[t.ID], t.Position)
+	}
+
+	var arrived []int
+	for _, id := range ids {
+		cells := covers[id]
+		old, onBoard := c.positions[id]
+		stayed := onBoard && sameCells(old, cells)
+		since := c.last
+		if stayed {
+			// Same cells, only the symbol changed.
+			since = c.occupants[id].Since
+		}
+		for _, pos := range old {
+			c.leave(id, pos)
+		}
+		delete(c.positions, id)
+		c.occupants[id] = Occupant{ID: id, Symbol: symbols[id], Since: since}
+		if len(cells) == 0 {
+			continue
+		}
+		c.positions[id] = cells
+		for _, pos := range cells {
+			c.cells[pos] = append(c.cells[pos], id)
+		}
+		if !stayed {
+			arrived = append(arrived, id)
+		}
+	}
+
+	var violations []Violation
+	reported := make(map[board.Position]bool)
+	for _, id := range arrived {
+		for _, pos := range c.positions[id] {
+			if reported[pos] {
+				continue
+			}
+			if v, bad := c.check(pos); bad {
+				violations = append(violations, v)
+				reported[pos] = true
+			}
+		}
+	}
+	return violations
+}
+
+func sameCells(a, b []board.Position) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Checker) leave(id int, pos board.Position) {
+	ids := c.cells[pos]
+	for i, other := range ids {
+		if other == id {
+			ids = append(ids[:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(c.cells, pos)
+	} else {
+		c.cells[pos] = ids
+	}
+}
+
+func (c *Checker) check(pos board.Position) (Violation, bool) {
+	ids := c.cells[pos]
+	if c.Capacity != nil && len(ids) <= c.Capacity(pos) {
+		return Violation{}, false
+	}
+	bad := false
+	for i := 0; i < len(ids) && !bad; i++ {
+		for j := i + 1; j < len(ids); j++ {
+			if !c.Allowed(c.occupants[ids[i]], c.occupants[ids[j]]) {
+				bad = true
+				break
+			}
+		}
+	}
+	if !bad {
+		return Violation{}, false
+	}
+	v := Violation{TimeStamp: c.last, Position: pos}
+	for _, id := range ids {
+		v.Occupants = append(v.Occupants, c.occupants[id])
+	}
+	sort.Slice(v.Occupants, func(i, j int) bool { return v.Occupants[i].ID < v.Occupants[j].ID })
+	return v, true
+}
+
+// Hook adapts c to board.AddHook, calling report for every violation.
+// Call Flush once the run is over to check the last timestamp.
+func (c *Checker) Hook(report func(Violation)) board.TraceHook {
+	return func(t board.Trace) {
+		for _, v := range c.Observe(t) {
+			report(v)
+		}
+	}
+}
+
+// FailFast writes v to stderr and ends the program.
+func FailFast(v Violation) {
+	fmt.Fprintln(os.Stderr, "occupancy violation:", v.Error())
+	os.Exit(1)
+}
+
+// Check runs a whole trace set, in any order, through a new checker.
+func Check(traces []board.Trace) []Violation {
+	return New().CheckAll(traces)
+}
+
+// CheckAll runs a whole trace set, in any order, through c.
+func (c *Checker) CheckAll(traces []board.Trace) []Violation {
+	sorted := append([]board.Trace(nil), traces...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].TimeStamp < sorted[j].TimeStamp })
+
+	var violations []Violation
+	for _, t := range sorted {
+		violations = append(violations, c.Observe(t)...)
+	}
+	return append(violations, c.Flush()...)
+}
+
+// event is one entity at one timestamp, what a CLOCK annotation belongs to.
+type event struct {
+	id    int
+	stamp time.Duration
+}
+
+// CheckLog runs the records of a parsed trace file through a new checker.
+// Cell capacities are taken from the FILL annotations of the log. A log
+// with CLOCK annotations is checked by CheckCausal.
+func CheckLog(log *tracefmt.Log) []Violation {
+	capacity := make(map[board.Position]int)
+	clocks := make(map[event]board.VectorClock)
+	for _, a := range log.Annotations {
+		switch a.Label {
+		case board.FillLabel:
+			var x, y, fill, k int
+			if _, err := fmt.Sscan(a.Payload, &x, &y, &fill, &k); err == nil {
+				capacity[board.Position{X: x, Y: y}] = k
+			}
+		case board.ClockLabel:
+			var id int
+			var clock string
+			if _, err := fmt.Sscan(a.Payload, &id, &clock); err != nil {
+				continue
+			}
+			if c, err := board.ParseVectorClock(clock); err == nil {
+				clocks[event{id, a.TimeStamp}] = c
+			}
+		}
+	}
+	c := New()
+	if len(capacity) > 0 {
+		c.Capacity = func(p board.Position) int {
+			if k, ok := capacity[p]; ok {
+				return k
+			}
+			return 1
+		}
+	}
+
+	traces := make([]board.Trace, len(log.Records))
+	for i, r := range log.Records {
+		traces[i] = board.Trace{
+			TimeStamp: r.TimeStamp,
+			ID:        r.ID,
+			Position:  board.Position{X: r.X, Y: r.Y},
+			Symbol:    r.Symbol,
+			Clock:     clocks[event{r.ID, r.TimeStamp}],
+		}
+	}
+	if len(clocks) > 0 {
+		return c.CheckCausal(traces)
+	}
+	return c.CheckAll(traces)
+}
+
+// visit is one entity on one cell, from the event that brought it there to
+// the first event of that entity elsewhere, nil while it stays.
+type visit struct {
+	occupant   Occupant
+	start, end board.VectorClock
+}
+
+// ordered reports whether a has left before b arrived, by their clocks.
+func (a visit) ordered(b visit) bool {
+	if a.end == nil {
+		return false
+	}
+	order := board.Compare(a.end, b.start)
+	return order == board.Before || order == board.Same
+}
+
+// CheckCausal checks traces by their vector clocks rather than timestamps:
+// two entities may use the same cell only if one left it before the other
+// arrived, in the happens-before order the clocks record. Visits the
+// clocks do not order are concurrent even when their timestamps happen to
+// be apart. Traces without a clock are ignored, as are cells with
+// Capacity above one.
+func (c *Checker) CheckCausal(traces []board.Trace) []Violation {
+	// Events of each entity in its own order; one event may cover cells.
+	byID := make(map[int][]board.Trace)
+	for _, t := range traces {
+		if t.Clock != nil {
+			byID[t.ID] = append(byID[t.ID], t)
+		}
+	}
+	visits := make(map[board.Position][]visit)
+	for id, own := range byID {
+		sort.SliceStable(own, func(i, j int) bool { return own[i].Clock[id] < own[j].Clock[id] })
+		here := make(map[board.Position]int)
+		var open []visit
+		for i := 0; i < len(own); {
+			event := own[i]
+			cells := make(map[board.Position]bool)
+			for ; i < len(own) && own[i].Clock[id] == event.Clock[id]; i++ {
+				if own[i].Position.X != -1 || own[i].Position.Y != -1 {
+					cells[own[i].Position] = true
+				}
+			}
+			for pos, k := range here {
+				if !cells[pos] {
+					open[k].end = event.Clock
+					visits[pos] = append(visits[pos], open[k])
+					delete(here, pos)
+				}
+			}
+			for pos := range cells {
+				if k, stays := here[pos]; stays {
+					open[k].occupant.Symbol = event.Symbol
+					continue
+				}
+				here[pos] = len(open)
+				open = append(open, visit{
+					occupant: Occupant{ID: id, Symbol: event.Symbol, Since: event.TimeStamp},
+					start:    event.Clock,
+				})
+			}
+		}
+		for pos, k := range here {
+			visits[pos] = append(visits[pos], open[k])
+		}
+	}
+
+	var violations []Violation
+	for pos, all := range visits {
+		if c.Capacity != nil && c.Capacity(pos) > 1 {
+			continue
+		}
+		for i, a := range all {
+			for _, b := range all[i+1:] {
+				if a.occupant.ID == b.occupant.ID || a.ordered(b) || b.ordered(a) || c.Allowed(a.occupant, b.occupant) {
+					continue
+				}
+				occupants := []Occupant{a.occupant, b.occupant}
+				sort.Slice(occupants, func(i, j int) bool { return occupants[i].ID < occupants[j].ID })
+				violations = append(violations, Violation{
+					TimeStamp: max(a.occupant.Since, b.occupant.Since),
+					Position:  pos,
+					Occupants: occupants,
+				})
+			}
+		}
+	}
+	sort.Slice(violations, func(i, j int) bool { return violations[i].TimeStamp < violations[j].TimeStamp })
+	return violations
+}
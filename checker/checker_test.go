@@ -0,0 +1,109 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dawid831/ParallelProgramming/tracefmt"
+)
+
+func checkLog(t *testing.T, input string) []string {
+	t.Helper()
+	log, err := tracefmt.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, v := range CheckLog(log) {
+		got = append(got, v.Error())
+	}
+	return got
+}
+
+func TestCheckLog(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		violations []string
+	}{
+		{"apart", `-1 2 3 3
+0.0 0 0 0 A
+0.0 1 2 2 B
+0.1 0 1 0 A
+0.2 1 1 1 B
+`, nil},
+		{"two travelers on a cell", `-1 2 3 3
+0.0 0 0 0 A
+0.0 1 2 2 B
+0.1 0 1 1 A
+0.2 1 1 1 B
+0.3 0 1 2 A
+`, []string{"0.200000000 cell (1, 1) shared by 0 (A since 0.100000000), 1 (B since 0.200000000)"}},
+		// listed out of time order: the check goes by timestamps, not lines
+		{"out of order", `-1 2 3 3
+0.2 1 1 1 B
+0.0 0 0 0 A
+0.1 0 1 1 A
+0.0 1 2 2 B
+`, []string{"0.200000000 cell (1, 1) shared by 0 (A since 0.100000000), 1 (B since 0.200000000)"}},
+		{"swap in one step", `-1 2 3 3
+0.0 0 0 0 A
+0.0 1 1 0 B
+0.1 0 1 0 A
+0.1 1 0 0 B
+`, nil},
+		{"caught on a trap", `-1 2 3 3
+0.0 1 1 1 #
+0.0 0 0 1 0
+0.1 0 1 1 *
+`, nil},
+		{"wild tenant leaves", `-1 2 3 3
+0.0 0 0 0 A
+0.0 1 1 0 0
+0.1 1 -1 -1 0
+0.2 0 1 0 A
+`, nil},
+		{"within capacity", `-1 3 3 3
+0.0 0 0 0 A
+0.0 1 2 0 B
+0.0 2 2 2 C
+0.1 0 1 1 A
+-2 0.1 FILL 1 1 1 2
+0.2 1 1 1 B
+-2 0.2 FILL 1 1 2 2
+0.3 2 1 1 C
+`, []string{"0.300000000 cell (1, 1) shared by 0 (A since 0.100000000), 1 (B since 0.200000000), 2 (C since 0.300000000)"}},
+		{"concurrent by clocks", `-1 2 3 3
+0.0 0 0 0 A
+-2 0.0 CLOCK 0 1,0
+0.0 1 2 2 B
+-2 0.0 CLOCK 1 0,1
+0.1 0 1 1 A
+-2 0.1 CLOCK 0 2,0
+0.5 0 1 2 A
+-2 0.5 CLOCK 0 3,0
+0.6 1 1 1 B
+-2 0.6 CLOCK 1 0,2
+`, []string{"0.600000000 cell (1, 1) shared by 0 (A since 0.100000000), 1 (B since 0.600000000)"}},
+		{"ordered by clocks", `-1 2 3 3
+0.0 0 0 0 A
+-2 0.0 CLOCK 0 1,0
+0.0 1 2 2 B
+-2 0.0 CLOCK 1 0,1
+0.1 0 1 1 A
+-2 0.1 CLOCK 0 2,0
+0.5 0 1 2 A
+-2 0.5 CLOCK 0 3,0
+0.6 1 1 1 B
+-2 0.6 CLOCK 1 3,2
+`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checkLog(t, tt.input)
+			if strings.Join(got, "\n") != strings.Join(tt.violations, "\n") {
+				t.Errorf("violations\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.violations, "\n"))
+			}
+		})
+	}
+}
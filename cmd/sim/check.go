@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/dawid831/ParallelProgramming/checker"
+	"github.com/dawid831/ParallelProgramming/tracefmt"
+)
+
+// runCheck runs "sim check [file]": the offline occupancy check of a trace
+// file, or of stdin, as written by the board scenarios.
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("sim check", flag.ExitOnError)
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, `usage: sim check [file]
+
+Reads the traces of a board scenario from file, or stdin, and lists every
+moment two entities shared a cell they must not: anything but a trap, or
+more travelers than the capacity its FILL annotations give. Traces with
+CLOCK annotations, from -clocks, are checked by their vector clocks. Exits
+with status 1 if there was a violation.
+`)
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	var r io.Reader = os.Stdin
+	name := "stdin"
+	if fs.NArg() == 1 {
+		name = fs.Arg(0)
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+	log, err := tracefmt.Parse(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+	violations := checker.CheckLog(log)
+	for _, v := range violations {
+		fmt.Println(v.Error())
+	}
+	if len(violations) > 0 {
+		fmt.Fprintf(os.Stderr, "%s: %d occupancy violations\n", name, len(violations))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,214 @@
+// Command sim runs every simulation of the course from one binary:
+//
+//	sim <scenario> [flags]
+//
+// The scenarios are the assignments of lista1 to lista4. Their parameters,
+// constants in the assignment files, are flags, and -out sends the traces
+// to a file. "sim <scenario> -help" lists the flags and describes the
+// traces the scenario writes.
+//
+//	sim run <file.json> [flags]
+//
+// runs a scenario file, a whole run described in JSON (see scenario.File),
+// the flags after it overriding the file, and
+//
+//	sim sweep [flags] <scenario | file.json> [flags]
+//
+// runs one over ranges of its flags, writing a CSV row of metrics per run.
+//
+//	sim check [file]
+//
+// checks the traces of a board scenario, from file or stdin, for cells
+// shared by entities that must not share them.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dawid831/ParallelProgramming/scenario"
+	"github.com/dawid831/ParallelProgramming/scenario/bakery"
+	"github.com/dawid831/ParallelProgramming/scenario/dekker"
+	"github.com/dawid831/ParallelProgramming/scenario/peterson"
+	"github.com/dawid831/ParallelProgramming/scenario/rwmonitor"
+	"github.com/dawid831/ParallelProgramming/scenario/szymanski"
+	"github.com/dawid831/ParallelProgramming/scenario/traps"
+	"github.com/dawid831/ParallelProgramming/scenario/travelers"
+	"github.com/dawid831/ParallelProgramming/scenario/travelersdirectional"
+	"github.com/dawid831/ParallelProgramming/scenario/travelerslocked"
+	"github.com/dawid831/ParallelProgramming/scenario/wildtenants"
+)
+
+// command is one scenario: the assignment it comes from, what it shows,
+// what its traces add to the common format and its Main.
+type command struct {
+	name, assignment, summary, traces string
+	main                              func()
+}
+
+var commands = []command{
+	{"travelers", "lista1/zadanie1", "travelers walk a board without any synchronization, so two may share a cell",
+		boardTraces, travelers.Main},
+	{"travelers-locked", "lista1/zadanie3", "travelers lock the cell they step on and free the one they leave",
+		boardTraces, travelerslocked.Main},
+	{"travelers-directional", "lista1/zadanie5", "travelers keep one direction, wait for a taken cell up to a timeout and report deadlocks",
+		boardTraces + `
+A cycle of travelers waiting for each other's cells is annotated once the
+run is over:
+
+	-2 TIMESTAMP DEADLOCK ID X Y;ID X Y;...
+`, travelersdirectional.Main},
+	{"wild-tenants", "lista2/zadanie2", "travelers push wild tenants aside on a board of cell actors",
+		boardTraces + wildTraces, wildtenants.Main},
+	{"traps", "lista2/zadanie4", "wild tenants and travelers among traps, moves committed in two phases, with snapshots of the running board",
+		boardTraces + wildTraces + `
+A trap is traced once where it lies, with symbol '#'. A wild tenant caught
+by one is traced with '*'.
+`, traps.Main},
+	{"bakery", "lista3/zadanie2", "mutual exclusion by Lamport's bakery algorithm",
+		processTraces("LOCAL_SECTION;ENTRY_PROTOCOL;CRITICAL_SECTION;EXIT_PROTOCOL;MAX_TICKET= T;",
+			"MAX_TICKET is the biggest ticket any process drew."), bakery.Main},
+	{"dekker", "lista3/zadanie4", "mutual exclusion of two processes by Dekker's algorithm",
+		processTraces("LOCAL_SECTION;ENTRY_PROTOCOL;CRITICAL_SECTION;EXIT_PROTOCOL;EXTRA_LABEL;", ""), dekker.Main},
+	{"peterson", "lista3/zadanie6", "mutual exclusion of two processes by Peterson's algorithm, or a search of the schedules for a run that breaks it",
+		processTraces("LOCAL_SECTION;ENTRY_PROTOCOL;CRITICAL_SECTION;EXIT_PROTOCOL;EXTRA_LABEL;",
+			"With -explore nothing is traced: the first schedule that lets both\nprocesses into the critical section is saved instead."), peterson.Main},
+	{"szymanski", "lista4/zadanie2", "mutual exclusion by Szymanski's algorithm",
+		processTraces("LOCAL_SECTION;ENTRY_PROTOCOL_1;ENTRY_PROTOCOL_2;ENTRY_PROTOCOL_3;ENTRY_PROTOCOL_4;CRITICAL_SECTION;EXIT_PROTOCOL;", ""), szymanski.Main},
+	{"rw-monitor", "lista4/zadanie4", "readers and writers share a reading room through a monitor",
+		processTraces("LOCAL_SECTION;START;READING_ROOM;STOP;",
+			"Readers have IDs from 0 and symbol R, the writers after them symbol W."), rwmonitor.Main},
+}
+
+const boardTraces = `
+Traces are lines of text. The first gives the parameters:
+
+	-1 N WIDTH HEIGHT TOPOLOGY= T;
+
+for N entities on a WIDTH x HEIGHT board; a torus3d board lays its layers
+side by side and adds DEPTH= D;. Every move of an entity follows as
+
+	TIMESTAMP ID X Y SYMBOL
+
+TIMESTAMP being seconds since the start. Travelers have capital letters,
+turned lower case when they give up or finish. Lines starting with -2 are
+annotations for the display:
+
+	-2 TIMESTAMP FILL X Y HOLDERS CAPACITY   on cells admitting several
+	-2 TIMESTAMP CLOCK ID V0,V1,...          the vector clock, with -clocks
+`
+
+const wildTraces = `
+Wild tenants have digits for symbols. One leaving the board is traced at
+X Y = -1 -1.
+`
+
+// processTraces describes the traces of the mutual exclusion scenarios,
+// whose parameter line ends with labels and comes last.
+func processTraces(labels, note string) string {
+	s := `
+Traces are lines of text, one per change of state of a process:
+
+	TIMESTAMP ID X Y SYMBOL
+
+TIMESTAMP being seconds since the start, X the process and Y its state,
+counted from 0 in the order of the labels. The parameters come last:
+
+	-1 N N STATES ` + labels + `
+`
+	if note != "" {
+		s += "\n" + note + "\n"
+	}
+	return s
+}
+
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "usage: sim <scenario> [flags]\n\nScenarios:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-22s %s (%s)\n", c.name, c.summary, c.assignment)
+	}
+	fmt.Fprintf(w, "\nRun \"sim <scenario> -help\" for its flags and traces.\n")
+	fmt.Fprintf(w, "\n\"sim run <file.json> [flags]\" runs the scenario a scenario file describes,\nthe flags overriding it; see the files in scenarios/.\n")
+	fmt.Fprintf(w, "\n\"sim sweep [flags] <scenario | file.json> [flags]\" runs it over ranges of its\nflags and writes a CSV of metrics, one row per run.\n")
+	fmt.Fprintf(w, "\n\"sim check [file]\" checks the traces of a board scenario for cells shared\nby entities that must not share them.\n")
+}
+
+// lookup returns the scenario named name.
+func lookup(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// run runs scenario c with the command line flags args.
+func run(c command, args []string) {
+	// Main of every scenario parses the default flag set from os.Args.
+	flag.CommandLine = flag.NewFlagSet("sim "+c.name, flag.ExitOnError)
+	flag.CommandLine.Usage = func() {
+		w := flag.CommandLine.Output()
+		fmt.Fprintf(w, "usage: sim %s [flags]\n\n%s%s, as %s.\n%s\nFlags:\n",
+			c.name, strings.ToUpper(c.summary[:1]), c.summary[1:], c.assignment, c.traces)
+		flag.PrintDefaults()
+	}
+	os.Args = append([]string{"sim " + c.name}, args...)
+	c.main()
+}
+
+// runFile runs the scenario file path, the flags in args coming after
+// those of the file and so overriding them.
+func runFile(path string, args []string) {
+	f, err := scenario.Load(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	c, ok := lookup(f.Scenario)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "%s: unknown scenario %q\n", path, f.Scenario)
+		os.Exit(2)
+	}
+	run(c, append(f.Args(), args...))
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	switch strings.TrimLeft(name, "-") {
+	case "help", "h":
+		flag.CommandLine.SetOutput(os.Stdout)
+		usage()
+		return
+	}
+	if name == "run" {
+		if len(os.Args) < 3 {
+			fmt.Fprintln(os.Stderr, "usage: sim run <file.json> [flags]")
+			os.Exit(2)
+		}
+		runFile(os.Args[2], os.Args[3:])
+		return
+	}
+	if name == "check" {
+		runCheck(os.Args[2:])
+		return
+	}
+	if name == "sweep" {
+		runSweep(os.Args[2:])
+		return
+	}
+	if c, ok := lookup(name); ok {
+		run(c, os.Args[2:])
+		return
+	}
+	fmt.Fprintf(os.Stderr, "sim: unknown scenario %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+
+	"github.com/dawid831/ParallelProgramming/scenario"
+	"github.com/dawid831/ParallelProgramming/sweep"
+)
+
+// runSweep runs "sim sweep [flags] <scenario | file.json> [flags]": the
+// scenario over the -vary ranges, -runs times per point, as processes of
+// this same binary.
+func runSweep(args []string) {
+	fs := flag.NewFlagSet("sim sweep", flag.ExitOnError)
+	var params sweep.Params
+	fs.Var(&params, "vary", "a flag of the scenario and its values, name=lo..hi[:step] or name=v1,v2,...; repeat for a grid of several")
+	runs := fs.Int("runs", 1, "runs of every point of the grid")
+	seed := fs.Int64("seed", 1, "seed of the first row; row i runs with -seed seed+i")
+	parallel := fs.Int("parallel", runtime.NumCPU(), "runs at a time")
+	out := fs.String("out", "", "write the CSV to `file` instead of stdout")
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, `usage: sim sweep [flags] <scenario | file.json> [flags of the scenario]
+
+Runs the scenario, or the scenario file, once per run of the sweep: every
+combination of the -vary values, -runs times each. The traces are not
+printed but measured, and the sweep writes one CSV row per run with the
+values of the -vary flags, the repetition, the seed and the metrics:
+entities, traces, duration_s of the last trace, letters (travelers or
+processes), lowercase (those of them ending lower case, having given up)
+and lowercase_rate, deadlocks, entered_<state> for every state of a
+process and the values of the parameter line, like max_ticket.
+
+Rows have seeds of their own, so give the scenario -virtual for runs that
+come out the same every time.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	scenario.Check(
+		scenario.AtLeast("runs", *runs, 1),
+		scenario.AtLeast("parallel", *parallel, 1),
+	)
+
+	// The base command line is that of "sim <scenario>" or "sim run".
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	base := fs.Arg(0)
+	command := []string{self, base}
+	if strings.HasSuffix(base, ".json") {
+		if _, err := scenario.Load(base); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		command = []string{self, "run", base}
+	} else if _, ok := lookup(base); !ok {
+		fmt.Fprintf(os.Stderr, "sim sweep: unknown scenario %q\n", base)
+		os.Exit(2)
+	}
+	command = append(command, fs.Args()[1:]...)
+
+	w, err := (&scenario.Output{Path: *out}).Open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	results := sweep.Sweep(ctx, command, params, sweep.Runs(params, *runs, *seed), *parallel)
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+	if err := sweep.WriteCSV(w, params, results); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d runs failed, see the error column\n", failed, len(results))
+	}
+}
@@ -0,0 +1,176 @@
+// Package deadlock keeps a live wait-for graph of travelers: a traveler
+// waits for a cell, the cell is held by another traveler. A cycle is reported
+// the moment the edge closing it is added.
+package deadlock
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/tracefmt"
+)
+
+// Label marks deadlock annotations in the trace stream.
+const Label = "DEADLOCK"
+
+// Cycle lists the travelers waiting on each other. Cells[i] is the cell
+// Members[i] waits for, held by Members[i+1] (wrapping around).
+type Cycle struct {
+	TimeStamp time.Duration
+	Members   []int
+	Cells     []board.Position
+}
+
+func (c Cycle) String() string {
+	parts := make([]string, len(c.Members))
+	for i, id := range c.Members {
+		parts[i] = fmt.Sprintf("%d waits for (%d, %d)", id, c.Cells[i].X, c.Cells[i].Y)
+	}
+	return fmt.Sprintf("%.9f deadlock: %s", c.TimeStamp.Seconds(), strings.Join(parts, ", "))
+}
+
+// Detector tracks which traveler holds and which waits for each cell.
+type Detector struct {
+	mu      sync.Mutex
+	board   *board.Board
+	holders map[board.Position]int
+	waits   map[int]board.Position
+	cycles  []Cycle
+	report  func(Cycle)
+}
+
+// New returns a detector stamping cycles with b's clock. report, if not nil,
+// is called for every cycle as soon as it forms.
+func New(b *board.Board, report func(Cycle)) *Detector {
+	return &Detector{
+		board:   b,
+		holders: make(map[board.Position]int),
+		waits:   make(map[int]board.Position),
+		report:  report,
+	}
+}
+
+// Hold records that id holds pos, e.g. its starting cell.
+func (d *Detector) Hold(id int, pos board.Position) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.holders[pos] = id
+}
+
+// Release records that id no longer holds pos. Call it before unlocking.
+func (d *Detector) Release(id int, pos board.Position) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.holders[pos] == id {
+		delete(d.holders, pos)
+	}
+}
+
+// Wait records that id waits for pos and checks for a cycle through id.
+func (d *Detector) Wait(id int, pos board.Position) {
+	d.mu.Lock()
+	d.waits[id] = pos
+	cycle, found := d.cycleFrom(id)
+	if found {
+		d.cycles = append(d.cycles, cycle)
+	}
+	d.mu.Unlock()
+
+	if found && d.report != nil {
+		d.report(cycle)
+	}
+}
+
+// Acquired records that id got the cell it waited for.
+func (d *Detector) Acquired(id int, pos board.Position) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.waits, id)
+	d.holders[pos] = id
+}
+
+// GiveUp records that id stopped waiting without the cell.
+func (d *Detector) GiveUp(id int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.waits, id)
+}
+
+// Cycles returns every cycle found so far.
+func (d *Detector) Cycles() []Cycle {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]Cycle(nil), d.cycles...)
+}
+
+// cycleFrom follows waits and holders from start. Every traveler waits for
+// at most one cell and every cell has at most one holder, so the walk either
+// ends or comes back to start.
+func (d *Detector) cycleFrom(start int) (Cycle, bool) {
+	cycle := Cycle{TimeStamp: d.board.Elapsed()}
+	seen := make(map[int]bool)
+	id := start
+	for {
+		pos, waiting := d.waits[id]
+		if !waiting || seen[id] {
+			return Cycle{}, false
+		}
+		seen[id] = true
+		cycle.Members = append(cycle.Members, id)
+		cycle.Cells = append(cycle.Cells, pos)
+
+		holder, held := d.holders[pos]
+		if !held {
+			return Cycle{}, false
+		}
+		if holder == start {
+			return cycle, true
+		}
+		id = holder
+	}
+}
+
+// PrintCycles writes one "-2 timestamp DEADLOCK id x y;..." annotation line
+// per cycle.
+func PrintCycles(w io.Writer, cycles []Cycle) {
+	for _, c := range cycles {
+		fmt.Fprintf(w, "-2 %.9f %s ", c.TimeStamp.Seconds(), Label)
+		for i, id := range c.Members {
+			fmt.Fprintf(w, "%d %d %d;", id, c.Cells[i].X, c.Cells[i].Y)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// ParseCycle reads a cycle back from a DEADLOCK annotation.
+func ParseCycle(a tracefmt.Annotation) (Cycle, error) {
+	if a.Label != Label {
+		return Cycle{}, fmt.Errorf("line %d: annotation %s is not %s", a.Line, a.Label, Label)
+	}
+	cycle := Cycle{TimeStamp: a.TimeStamp}
+	for _, member := range strings.Split(a.Payload, ";") {
+		fields := strings.Fields(member)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return Cycle{}, fmt.Errorf("line %d: member %q: want \"id x y\"", a.Line, member)
+		}
+		var ints [3]int
+		for i, f := range fields {
+			n, err := strconv.Atoi(f)
+			if err != nil {
+				return Cycle{}, fmt.Errorf("line %d: member %q: %q is not an integer", a.Line, member, f)
+			}
+			ints[i] = n
+		}
+		cycle.Members = append(cycle.Members, ints[0])
+		cycle.Cells = append(cycle.Cells, board.Position{X: ints[1], Y: ints[2]})
+	}
+	return cycle, nil
+}
@@ -0,0 +1,103 @@
+package deadlock
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/tracefmt"
+)
+
+func cell(x, y int) board.Position { return board.Position{X: x, Y: y} }
+
+// ring makes travelers 0..n-1 hold cells (i, 0) and each wait for the cell
+// of the next, the last wait closing the cycle.
+func ring(t *testing.T, n int) (*Detector, []Cycle) {
+	t.Helper()
+	var reported []Cycle
+	d := New(board.New(n, 1), func(c Cycle) { reported = append(reported, c) })
+	for i := range n {
+		d.Hold(i, cell(i, 0))
+	}
+	for i := range n - 1 {
+		d.Wait(i, cell(i+1, 0))
+		if len(d.Cycles()) != 0 {
+			t.Fatalf("cycle before the last wait: %v", d.Cycles())
+		}
+	}
+	d.Wait(n-1, cell(0, 0))
+	return d, reported
+}
+
+func TestCycles(t *testing.T) {
+	for _, n := range []int{2, 3} {
+		d, reported := ring(t, n)
+		cycles := d.Cycles()
+		if len(cycles) != 1 || len(reported) != 1 {
+			t.Fatalf("%d travelers: cycles %v, reported %v", n, cycles, reported)
+		}
+		// found from the traveler whose wait closed it
+		c := cycles[0]
+		var members []int
+		var cells []board.Position
+		for i := range n {
+			members = append(members, (n-1+i)%n)
+			cells = append(cells, cell((n+i)%n, 0))
+		}
+		if !slices.Equal(c.Members, members) || !slices.Equal(c.Cells, cells) {
+			t.Errorf("%d travelers: cycle %v, want members %v waiting for %v", n, c, members, cells)
+		}
+	}
+}
+
+func TestGiveUpAndReleaseBreakTheCycle(t *testing.T) {
+	d, _ := ring(t, 3)
+
+	// 1 gives up, and waiting again for the cell 2 has released closes
+	// no cycle
+	d.GiveUp(1)
+	d.Release(2, cell(2, 0))
+	d.Wait(1, cell(2, 0))
+	if len(d.Cycles()) != 1 {
+		t.Errorf("cycles %v after the ring broke, want only the first", d.Cycles())
+	}
+
+	// 2 holds its cell again, so 1 waiting for it closes the ring anew
+	d.Hold(2, cell(2, 0))
+	d.Wait(1, cell(2, 0))
+	if len(d.Cycles()) != 2 {
+		t.Errorf("cycles %v, want the ring found again", d.Cycles())
+	}
+}
+
+func TestPrintAndParseCycles(t *testing.T) {
+	d, _ := ring(t, 3)
+	var out strings.Builder
+	PrintCycles(&out, d.Cycles())
+	a, err := tracefmt.ParseAnnotation(strings.TrimSpace(out.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ParseCycle(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := d.Cycles()[0]; !slices.Equal(c.Members, want.Members) || !slices.Equal(c.Cells, want.Cells) {
+		t.Errorf("parsed %v, printed %v", c, want)
+	}
+}
+
+func TestAcquiredEndsTheWait(t *testing.T) {
+	d := New(board.New(2, 1), nil)
+	d.Hold(0, cell(0, 0))
+	d.Wait(0, cell(1, 0))
+	d.Acquired(0, cell(1, 0))
+	d.Release(0, cell(0, 0))
+	// 1 waits for the cell 0 moved to, 0 waits for nothing
+	d.Wait(1, cell(1, 0))
+	d.Hold(1, cell(0, 0))
+	if len(d.Cycles()) != 0 {
+		t.Errorf("cycles %v", d.Cycles())
+	}
+}
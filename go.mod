@@ -0,0 +1,3 @@
+module github.com/dawid831/ParallelProgramming
+
+go 1.22
@@ -0,0 +1,74 @@
+package replay
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Options are the -seed, -record and -replay command line flags.
+type Options struct {
+	Seed       int64
+	RecordPath string
+	ReplayPath string
+}
+
+// RegisterFlags adds the replay flags to the default flag set.
+func RegisterFlags() *Options {
+	o := &Options{}
+	flag.Int64Var(&o.Seed, "seed", 0, "seed for all random decisions (0 picks one from the clock)")
+	flag.StringVar(&o.RecordPath, "record", "", "write the decision log to this file")
+	flag.StringVar(&o.ReplayPath, "replay", "", "replay the decision log from this file")
+	return o
+}
+
+// UseSeed makes the run go on with seed, the seed of a checkpoint it
+// resumes. A -seed given as well has to agree.
+func (o *Options) UseSeed(seed int64) error {
+	if o.Seed != 0 && o.Seed != seed {
+		return fmt.Errorf("-seed %d, but the checkpoint was taken with seed %d", o.Seed, seed)
+	}
+	o.Seed = seed
+	return nil
+}
+
+// Open returns the log for this run. The seed in use is written to stderr so
+// any run can be repeated with -seed.
+func (o *Options) Open() (*Log, error) {
+	var log *Log
+	if o.ReplayPath != "" {
+		f, err := os.Open(o.ReplayPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if log, err = Read(f); err != nil {
+			return nil, fmt.Errorf("%s: %v", o.ReplayPath, err)
+		}
+	} else {
+		seed := o.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		log = NewLog(seed)
+	}
+	fmt.Fprintf(os.Stderr, "seed %d\n", log.Seed)
+	return log, nil
+}
+
+// Close writes the recorded decisions if -record was given.
+func (o *Options) Close(log *Log) error {
+	if o.RecordPath == "" {
+		return nil
+	}
+	f, err := os.Create(o.RecordPath)
+	if err != nil {
+		return err
+	}
+	if err := log.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
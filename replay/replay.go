@@ -0,0 +1,295 @@
+// Package replay makes traveler runs deterministic. Every entity rolls its
+// random decisions through its own seeded Dice, and all rolls can be
+// recorded into a Log and fed back later to repeat the same run.
+package replay
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/board"
+)
+
+// SetupID is the entity ID used for decisions made by main, like initial
+// placement of travelers and traps.
+const SetupID = -1
+
+type Kind int
+
+const (
+	Int Kind = iota
+	Dir
+	Delay
+)
+
+func (k Kind) String() string {
+	return [...]string{"int", "dir", "delay"}[k]
+}
+
+// Decision is one roll of one entity.
+type Decision struct {
+	ID    int
+	Kind  Kind
+	Value int64
+}
+
+// Log collects decisions of all entities in the order they were rolled.
+type Log struct {
+	Seed      int64
+	mu        sync.Mutex
+	decisions []Decision
+	replaying bool
+}
+
+// NewLog starts recording a run seeded with seed.
+func NewLog(seed int64) *Log {
+	return &Log{Seed: seed}
+}
+
+// Dice returns the dice of entity id. When the log was read from a file the
+// dice replay that entity's decisions, otherwise they roll and record.
+func (l *Log) Dice(id int) *Dice {
+	return l.Resume(id, 0)
+}
+
+// Resume returns the dice of entity id as they were after draws values had
+// been drawn from its source, so a checkpointed run goes on rolling what
+// the interrupted one would have.
+func (l *Log) Resume(id int, draws uint64) *Dice {
+	src := newSource(l.Seed, id)
+	for src.draws < draws {
+		src.Int63()
+	}
+	d := &Dice{id: id, src: src, r: rand.New(src), log: l}
+	if l.replaying {
+		l.mu.Lock()
+		for _, decision := range l.decisions {
+			if decision.ID == id {
+				d.pending = append(d.pending, decision)
+			}
+		}
+		l.mu.Unlock()
+	}
+	return d
+}
+
+func (l *Log) record(decision Decision) {
+	if l.replaying {
+		return
+	}
+	l.mu.Lock()
+	l.decisions = append(l.decisions, decision)
+	l.mu.Unlock()
+}
+
+// Decisions returns a copy of everything recorded so far.
+func (l *Log) Decisions() []Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Decision(nil), l.decisions...)
+}
+
+// Write writes the log as a "seed S" line followed by one
+// "id kind value" line per decision.
+func (l *Log) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "seed %d\n", l.Seed)
+	for _, d := range l.Decisions() {
+		value := strconv.FormatInt(d.Value, 10)
+		if d.Kind == Dir {
+			value = board.Direction(d.Value).String()
+		}
+		fmt.Fprintf(bw, "%d %s %s\n", d.ID, d.Kind, value)
+	}
+	return bw.Flush()
+}
+
+// Read loads a log written by Write for replaying.
+func Read(r io.Reader) (*Log, error) {
+	l := &Log{replaying: true}
+	scanner := bufio.NewScanner(r)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if lineNr == 1 {
+			if len(fields) != 2 || fields[0] != "seed" {
+				return nil, fmt.Errorf("line 1: want \"seed S\", got %q", scanner.Text())
+			}
+			seed, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line 1: bad seed %q", fields[1])
+			}
+			l.Seed = seed
+			continue
+		}
+		decision, err := parseDecision(fields)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNr, err)
+		}
+		l.decisions = append(l.decisions, decision)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if lineNr == 0 {
+		return nil, fmt.Errorf("empty decision log")
+	}
+	return l, nil
+}
+
+func parseDecision(fields []string) (Decision, error) {
+	if len(fields) != 3 {
+		return Decision{}, fmt.Errorf("want \"id kind value\", got %q", strings.Join(fields, " "))
+	}
+	id, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return Decision{}, fmt.Errorf("bad id %q", fields[0])
+	}
+	decision := Decision{ID: id}
+	switch fields[1] {
+	case Int.String():
+		decision.Kind = Int
+	case Delay.String():
+		decision.Kind = Delay
+	case Dir.String():
+		decision.Kind = Dir
+		d, err := board.ParseDirection(fields[2])
+		if err != nil {
+			return Decision{}, err
+		}
+		decision.Value = int64(d)
+		return decision, nil
+	default:
+		return Decision{}, fmt.Errorf("bad kind %q", fields[1])
+	}
+	decision.Value, err = strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Decision{}, fmt.Errorf("bad value %q", fields[2])
+	}
+	return decision, nil
+}
+
+// Dice rolls the decisions of a single entity. A Dice must only be used by
+// one goroutine at a time.
+type Dice struct {
+	id      int
+	src     *countingSource
+	r       *rand.Rand
+	log     *Log
+	pending []Decision
+}
+
+// Draws is how many values the dice have drawn from their source, the
+// whole state of the generator given the seed. Replayed rolls draw none.
+func (d *Dice) Draws() uint64 {
+	return d.src.draws
+}
+
+// countingSource counts the values drawn, each of which advances the
+// generator by one step.
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func newSource(seed int64, id int) *countingSource {
+	return &countingSource{src: rand.NewSource(Mix(seed, id)).(rand.Source64)}
+}
+
+// Mix is the seed of the source of entity id in a run seeded with seed.
+// Adding the two would give seed s with entity i+1 the stream of seed s+1
+// with entity i, so runs with nearby seeds would share their streams;
+// splitmix64 over the seed and then the id keeps every pair apart.
+func Mix(seed int64, id int) int64 {
+	return int64(splitmix64(splitmix64(uint64(seed)) + uint64(id)))
+}
+
+func splitmix64(z uint64) uint64 {
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return s.src.Uint64()
+}
+
+func (s *countingSource) Seed(seed int64) {
+	s.src.Seed(seed)
+	s.draws = 0
+}
+
+func (d *Dice) roll(kind Kind, fresh func() int64) int64 {
+	if d.log != nil && d.log.replaying {
+		if len(d.pending) == 0 {
+			panic(fmt.Sprintf("replay: entity %d rolled %s past the end of the log", d.id, kind))
+		}
+		next := d.pending[0]
+		if next.Kind != kind {
+			panic(fmt.Sprintf("replay: entity %d rolled %s, log has %s", d.id, kind, next.Kind))
+		}
+		d.pending = d.pending[1:]
+		return next.Value
+	}
+	value := fresh()
+	if d.log != nil {
+		d.log.record(Decision{ID: d.id, Kind: kind, Value: value})
+	}
+	return value
+}
+
+// Intn returns a number in [0, n).
+func (d *Dice) Intn(n int) int {
+	return int(d.roll(Int, func() int64 { return int64(d.r.Intn(n)) }))
+}
+
+// Direction rolls one of dirs.
+func (d *Dice) Direction(dirs []board.Direction) board.Direction {
+	return board.Direction(d.roll(Dir, func() int64 { return int64(dirs[d.r.Intn(len(dirs))]) }))
+}
+
+// Delay rolls a sleep in [min, max). An empty range is min, not rolled.
+func (d *Dice) Delay(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return time.Duration(d.roll(Delay, func() int64 { return int64(min) + d.r.Int63n(int64(max-min)) }))
+}
+
+// Position rolls a cell of b, rolling again on walls. The layer is only
+// rolled on 3D boards.
+func (d *Dice) Position(b *board.Board) board.Position {
+	for {
+		p := board.Position{X: d.Intn(b.Width), Y: d.Intn(b.Height)}
+		if b.Depth > 1 {
+			p.Z = d.Intn(b.Depth)
+		}
+		if !b.Wall(p) {
+			return p
+		}
+	}
+}
+
+// Shuffle permutes n elements with recorded swaps.
+func (d *Dice) Shuffle(n int, swap func(i, j int)) {
+	for i := n - 1; i > 0; i-- {
+		swap(i, d.Intn(i+1))
+	}
+}
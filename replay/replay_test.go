@@ -0,0 +1,41 @@
+package replay
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNearbySeedsGiveEntitiesTheirOwnStreams(t *testing.T) {
+	seen := make(map[int64]string)
+	for seed := int64(-2); seed <= 2; seed++ {
+		for id := SetupID; id < 4; id++ {
+			mixed := Mix(seed, id)
+			if other, ok := seen[mixed]; ok {
+				t.Errorf("seed %d entity %d has the source of %s", seed, id, other)
+			}
+			seen[mixed] = fmt.Sprintf("seed %d entity %d", seed, id)
+		}
+	}
+	a, b := NewLog(1).Dice(1), NewLog(2).Dice(0)
+	if a.Intn(1<<30) == b.Intn(1<<30) && a.Intn(1<<30) == b.Intn(1<<30) {
+		t.Error("seed 1 entity 1 rolls what seed 2 entity 0 does")
+	}
+}
+
+func TestDelayOfAnEmptyRange(t *testing.T) {
+	d := NewLog(1).Dice(0)
+	for _, max := range []time.Duration{10 * time.Millisecond, 5 * time.Millisecond} {
+		if got := d.Delay(10*time.Millisecond, max); got != 10*time.Millisecond {
+			t.Errorf("Delay(10ms, %v) = %v, want 10ms", max, got)
+		}
+	}
+	if d.Draws() != 0 {
+		t.Errorf("%d draws for empty ranges", d.Draws())
+	}
+	for range 100 {
+		if got := d.Delay(10*time.Millisecond, 12*time.Millisecond); got < 10*time.Millisecond || got >= 12*time.Millisecond {
+			t.Fatalf("Delay(10ms, 12ms) = %v", got)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package scenario
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/board"
+)
+
+// File is a scenario file: one run of a simulation described in JSON, so
+// that it can be checked in, diffed and run again. It is turned into the
+// command line flags of the scenario, so a file runs the same code as the
+// flags would. Sections left out keep the defaults of the scenario.
+type File struct {
+	// Scenario is the subcommand of sim to run. It may be left out when
+	// Algorithm names it.
+	Scenario string `json:",omitempty"`
+	// Seed and Virtual are -seed and -virtual.
+	Seed    int64 `json:",omitempty"`
+	Virtual bool  `json:",omitempty"`
+	Steps   *struct {
+		Min, Max int
+	} `json:",omitempty"`
+	Delays *struct {
+		Min, Max Duration
+	} `json:",omitempty"`
+	Board       *BoardSection       `json:",omitempty"`
+	Travelers   *TravelersSection   `json:",omitempty"`
+	WildTenants *WildTenantsSection `json:",omitempty"`
+	Traps       *TrapsSection       `json:",omitempty"`
+	Algorithm   *AlgorithmSection   `json:",omitempty"`
+	// Out is -out, relative to the directory sim runs in.
+	Out string `json:",omitempty"`
+	// Flags are any other flags of the scenario, by name without the
+	// dash.
+	Flags map[string]string `json:",omitempty"`
+}
+
+// BoardSection is the board of the lista1 and lista2 scenarios. Map is
+// relative to the directory of the file.
+type BoardSection struct {
+	Width, Height int    `json:",omitempty"`
+	Topology      string `json:",omitempty"`
+	Depth         int    `json:",omitempty"`
+	Map           string `json:",omitempty"`
+	Capacity      int    `json:",omitempty"`
+}
+
+// TravelersSection gives the travelers, with the -strategy and -shape
+// specs given to them in turn. Starts places the first of them; the rest
+// start where the map or the scenario puts them.
+type TravelersSection struct {
+	Count    int              `json:",omitempty"`
+	Starts   []board.Position `json:",omitempty"`
+	Strategy string           `json:",omitempty"`
+	Shape    string           `json:",omitempty"`
+}
+
+// WildTenantsSection gives the wild tenants of the lista2 scenarios.
+type WildTenantsSection struct {
+	Count    *int     `json:",omitempty"`
+	Lifetime Duration `json:",omitempty"`
+}
+
+// TrapsSection gives the traps of the traps scenario. Count defaults to
+// the number of Positions, the traps past them lie on random cells. There
+// is one kind of trap, so a trap is only a position.
+type TrapsSection struct {
+	Count     *int             `json:",omitempty"`
+	Positions []board.Position `json:",omitempty"`
+}
+
+// AlgorithmSection picks the mutual exclusion scenario of lista3 or
+// lista4 and its processes: Processes for bakery and szymanski, Readers
+// and Writers for rw-monitor.
+type AlgorithmSection struct {
+	Name             string `json:",omitempty"`
+	Processes        int    `json:",omitempty"`
+	Readers, Writers *int   `json:",omitempty"`
+}
+
+// Duration is a time.Duration written as in flags, "10ms", or as a number
+// of nanoseconds.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("duration %s: want a string like \"10ms\" or nanoseconds", b)
+		}
+		*d = Duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Read decodes a scenario file. A misspelt field is an error rather than
+// a section silently left at its defaults.
+func Read(r io.Reader) (*File, error) {
+	var f File
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	if f.Algorithm != nil && f.Algorithm.Name != "" {
+		if f.Scenario != "" && f.Scenario != f.Algorithm.Name {
+			return nil, fmt.Errorf("scenario %s runs no algorithm %s", f.Scenario, f.Algorithm.Name)
+		}
+		f.Scenario = f.Algorithm.Name
+	}
+	if f.Scenario == "" {
+		return nil, errors.New("no scenario, and no algorithm to name one")
+	}
+	return &f, nil
+}
+
+// Load reads the scenario file at path and makes the map it names
+// relative to the directory sim runs in.
+func Load(path string) (*File, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	f, err := Read(r)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if f.Board != nil && f.Board.Map != "" && !filepath.IsAbs(f.Board.Map) {
+		f.Board.Map = filepath.Join(filepath.Dir(path), f.Board.Map)
+	}
+	return f, nil
+}
+
+// Args returns the command line flags f stands for, to be parsed by the
+// Main of f.Scenario.
+func (f *File) Args() []string {
+	var args []string
+	set := func(name string, value any) {
+		args = append(args, fmt.Sprintf("-%s=%v", name, value))
+	}
+	if f.Seed != 0 {
+		set("seed", f.Seed)
+	}
+	if f.Virtual {
+		set("virtual", true)
+	}
+	if s := f.Steps; s != nil {
+		set("min-steps", s.Min)
+		set("max-steps", s.Max)
+	}
+	if d := f.Delays; d != nil {
+		set("min-delay", time.Duration(d.Min))
+		set("max-delay", time.Duration(d.Max))
+	}
+	if b := f.Board; b != nil {
+		if b.Width != 0 {
+			set("width", b.Width)
+		}
+		if b.Height != 0 {
+			set("height", b.Height)
+		}
+		if b.Topology != "" {
+			set("topology", b.Topology)
+		}
+		if b.Depth != 0 {
+			set("depth", b.Depth)
+		}
+		if b.Map != "" {
+			set("map", b.Map)
+		}
+		if b.Capacity != 0 {
+			set("capacity", b.Capacity)
+		}
+	}
+	if t := f.Travelers; t != nil {
+		if t.Count != 0 {
+			set("travelers", t.Count)
+		}
+		if len(t.Starts) != 0 {
+			starts := Positions(t.Starts)
+			set("starts", starts.String())
+		}
+		if t.Strategy != "" {
+			set("strategy", t.Strategy)
+		}
+		if t.Shape != "" {
+			set("shape", t.Shape)
+		}
+	}
+	if w := f.WildTenants; w != nil {
+		if w.Count != nil {
+			set("wild-tenants", *w.Count)
+		}
+		if w.Lifetime != 0 {
+			set("wild-tenant-lifetime", time.Duration(w.Lifetime))
+		}
+	}
+	if t := f.Traps; t != nil {
+		switch {
+		case t.Count != nil:
+			set("traps", *t.Count)
+		case len(t.Positions) != 0:
+			set("traps", len(t.Positions))
+		}
+		if len(t.Positions) != 0 {
+			at := Positions(t.Positions)
+			set("trap-at", at.String())
+		}
+	}
+	if a := f.Algorithm; a != nil {
+		if a.Processes != 0 {
+			set("processes", a.Processes)
+		}
+		if a.Readers != nil {
+			set("readers", *a.Readers)
+		}
+		if a.Writers != nil {
+			set("writers", *a.Writers)
+		}
+	}
+	if f.Out != "" {
+		set("out", f.Out)
+	}
+	names := make([]string, 0, len(f.Flags))
+	for name := range f.Flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		set(name, f.Flags[name])
+	}
+	return args
+}
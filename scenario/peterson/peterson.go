@@ -0,0 +1,395 @@
+// Package peterson is lista3 zadanie6: mutual exclusion of two processes
+// by Peterson's algorithm, with a search of the schedules for a run that
+// breaks it.
+package peterson
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/replay"
+	"github.com/dawid831/ParallelProgramming/scenario"
+	"github.com/dawid831/ParallelProgramming/simtime"
+)
+
+// NrOfProcesses is fixed: the algorithm is one for two processes.
+const NrOfProcesses = 2
+
+// Parameters, the former constants; Main sets them from its flags.
+var (
+	MinSteps = 150
+	MaxSteps = 300
+	MinDelay = 10 * time.Millisecond
+	MaxDelay = 50 * time.Millisecond
+)
+
+// out receives the traces, stdout or the file of -out.
+var out io.Writer = os.Stdout
+
+// ProcessState represents the states of a process
+type ProcessState int
+
+const (
+	LocalSection ProcessState = iota
+	EntryProtocol
+	CriticalSection
+	ExitProtocol
+)
+
+// Board dimensions
+const (
+	BoardWidth  = NrOfProcesses
+	BoardHeight = int(ExitProtocol) + 1
+)
+
+// Global variables
+var (
+	clock simtime.Clock
+	c1    int32 = 0
+	c2    int32 = 0
+	last  int32 = 1
+	// inCritical counts the processes in the critical section, overlaps
+	// the times a process entered it while the other was there.
+	inCritical int32
+	overlaps   int32
+)
+
+// store writes a shared variable. Each write is a point where the clock may
+// let the other process run first; the reads in the waits are the points
+// where a process looks again.
+func store(v *int32, value int32) {
+	clock.Yield()
+	atomic.StoreInt32(v, value)
+}
+
+// PositionType represents positions on the board
+type PositionType struct {
+	X int // 0..BoardWidth-1
+	Y int // 0..BoardHeight-1
+}
+
+// TraceType represents traces of processes
+type TraceType struct {
+	TimeStamp time.Duration
+	ID        int
+	Position  PositionType
+	Symbol    rune
+}
+
+// TracesSequenceType represents a sequence of traces
+type TracesSequenceType struct {
+	Last       int
+	TraceArray []TraceType
+}
+
+// ProcessType represents a process
+type ProcessType struct {
+	ID       int
+	Symbol   rune
+	Position PositionType
+}
+
+// ProcessTask represents a process task
+type ProcessTask struct {
+	process     ProcessType
+	rand        *rand.Rand
+	nrOfSteps   int
+	traces      TracesSequenceType
+	tracesMutex sync.Mutex
+	initDone    chan struct{}
+	startDone   chan struct{}
+	reportDone  chan struct{}
+}
+
+// NewProcessTask creates a new ProcessTask
+func NewProcessTask(id int, seed int64, symbol rune) *ProcessTask {
+	r := rand.New(rand.NewSource(seed))
+	return &ProcessTask{
+		process: ProcessType{
+			ID:     id,
+			Symbol: symbol,
+			Position: PositionType{
+				X: id,
+				Y: int(LocalSection),
+			},
+		},
+		rand:      r,
+		nrOfSteps: MinSteps + r.Intn(MaxSteps-MinSteps+1),
+		traces:    TracesSequenceType{Last: -1, TraceArray: make([]TraceType, MaxSteps+1)},
+
+		initDone:   make(chan struct{}),
+		startDone:  make(chan struct{}),
+		reportDone: make(chan struct{}, 1),
+	}
+}
+
+// Init initializes the process task
+func (pt *ProcessTask) Init() {
+	// Store initial position
+	pt.storeTrace()
+	close(pt.initDone)
+}
+
+// Start starts the process task
+func (pt *ProcessTask) Start() {
+	<-pt.initDone
+	close(pt.startDone)
+}
+
+// Run runs the process task
+func (pt *ProcessTask) Run() {
+	<-pt.startDone
+
+	i := pt.process.ID
+
+	for step := 0; step < pt.nrOfSteps/4; step++ { // Adjusted for testing
+		// LOCAL_SECTION - start
+		delay := MinDelay + time.Duration(float64(MaxDelay-MinDelay)*pt.rand.Float64())
+		clock.Sleep(delay)
+		// LOCAL_SECTION - end
+
+		pt.changeState(EntryProtocol) // starting ENTRY_PROTOCOL
+
+		if i == 0 {
+			store(&c1, 1)
+			store(&last, 1)
+			clock.Wait(-1, func() bool {
+				return atomic.LoadInt32(&c2) == 0 || atomic.LoadInt32(&last) != 1
+			})
+		} else {
+			store(&c2, 1)
+			store(&last, 2)
+			clock.Wait(-1, func() bool {
+				return atomic.LoadInt32(&c1) == 0 || atomic.LoadInt32(&last) != 2
+			})
+		}
+
+		pt.changeState(CriticalSection) // starting CRITICAL_SECTION
+		if atomic.AddInt32(&inCritical, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+
+		// CRITICAL_SECTION - start
+		delay = MinDelay + time.Duration(float64(MaxDelay-MinDelay)*pt.rand.Float64())
+		clock.Sleep(delay)
+		// CRITICAL_SECTION - end
+
+		atomic.AddInt32(&inCritical, -1)
+		pt.changeState(ExitProtocol) // starting EXIT_PROTOCOL
+		if i == 0 {
+			store(&c1, 0)
+		} else {
+			store(&c2, 0)
+		}
+		pt.changeState(LocalSection) // starting LOCAL_SECTION
+	}
+
+	pt.reportDone <- struct{}{}
+}
+
+func (pt *ProcessTask) storeTrace() {
+	pt.tracesMutex.Lock()
+	defer pt.tracesMutex.Unlock()
+
+	pt.traces.Last++
+	pt.traces.TraceArray[pt.traces.Last] = TraceType{
+		TimeStamp: clock.Now(),
+		ID:        pt.process.ID,
+		Position:  pt.process.Position,
+		Symbol:    pt.process.Symbol,
+	}
+}
+
+func (pt *ProcessTask) changeState(state ProcessState) {
+	pt.process.Position.Y = int(state)
+	pt.storeTrace()
+}
+
+// Printer collects and prints reports of traces
+func printer(reportChan <-chan TracesSequenceType, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	// Collect and print the traces
+	for i := 0; i < NrOfProcesses; i++ {
+		traces := <-reportChan
+		printTraces(traces)
+	}
+
+	// Print the line with the parameters needed for display script
+	fmt.Fprintf(out, "-1 %d %d %d ", NrOfProcesses, BoardWidth, BoardHeight)
+	for state := LocalSection; state <= ExitProtocol; state++ {
+		fmt.Fprintf(out, "%s;", state)
+	}
+	fmt.Fprintln(out, "EXTRA_LABEL;") // Place labels with extra info here
+}
+
+func printTrace(trace TraceType) {
+	fmt.Fprintf(out, "%.9f %d %d %d %c\n",
+		trace.TimeStamp.Seconds(),
+		trace.ID,
+		trace.Position.X,
+		trace.Position.Y,
+		trace.Symbol)
+}
+
+func printTraces(traces TracesSequenceType) {
+	for i := 0; i <= traces.Last; i++ {
+		printTrace(traces.TraceArray[i])
+	}
+}
+
+// simulate runs the processes once, seeded from seed, and reports whether
+// they ever were in the critical section together.
+func simulate(seed int64) ([]*ProcessTask, error) {
+	c1, c2, last = 0, 0, 1
+	inCritical, overlaps = 0, 0
+
+	// Create process tasks
+	processTasks := make([]*ProcessTask, NrOfProcesses)
+	symbol := 'A'
+
+	for i := 0; i < NrOfProcesses; i++ {
+		processTasks[i] = NewProcessTask(i, replay.Mix(seed, i), symbol)
+		symbol++
+	}
+
+	// Initialize process tasks
+	for _, pt := range processTasks {
+		pt.Init()
+	}
+
+	// Start process tasks
+	for _, pt := range processTasks {
+		pt.Start()
+	}
+
+	// Run process tasks and wait for them to finish
+	for _, pt := range processTasks {
+		clock.Go(pt.Run)
+	}
+	clock.Start()
+	for _, pt := range processTasks {
+		<-pt.reportDone
+	}
+
+	if overlaps > 0 {
+		return processTasks, fmt.Errorf("both processes in the critical section %d times", overlaps)
+	}
+	return processTasks, nil
+}
+
+// explore runs the processes under many schedules of the clock and saves
+// the first one that breaks mutual exclusion to path.
+func explore(spec string, scheduleSeed, seed int64, runs int, path string) error {
+	if scheduleSeed == 0 {
+		scheduleSeed = time.Now().UnixNano()
+	}
+	failure, done, err := simtime.Explore(spec, scheduleSeed, runs, func(c simtime.Clock) error {
+		clock = c
+		_, err := simulate(seed)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if failure == nil {
+		fmt.Fprintf(os.Stderr, "no violation in %d runs (seed %d, schedule seed %d)\n", done, seed, scheduleSeed)
+		return nil
+	}
+	if path == "" {
+		path = "failing.schedule"
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	note := fmt.Sprintf("peterson -seed %d, run %d of -explore %s: %v\nreplay with -seed %d -schedule-replay %s",
+		seed, failure.Run, spec, failure.Err, seed, path)
+	if err := simtime.WriteSchedule(f, note, failure.Choices); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return fmt.Errorf("run %d: %v; schedule saved to %s", failure.Run, failure.Err, path)
+}
+
+// Main runs the simulation with the parameters given on the command line.
+func Main() {
+	scenario.Steps(&MinSteps, &MaxSteps)
+	scenario.Delays(&MinDelay, &MaxDelay)
+	output := scenario.RegisterOutput()
+	timeOptions := simtime.RegisterFlags()
+	seedOption := scenario.RegisterSeed()
+	exploreSpec := flag.String("explore", "", "search the schedules for a violation of mutual exclusion instead of one run: random, pct[:depth[:steps]] or dfs[:depth]")
+	runs := flag.Int("runs", 1000, "most runs of -explore")
+	flag.Parse()
+	scenario.Check(
+		scenario.Range("steps", MinSteps, MaxSteps),
+		scenario.Range("delays", MinDelay, MaxDelay),
+	)
+	var err error
+	if out, err = output.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	seed := seedOption.Pick()
+	if *exploreSpec != "" {
+		if err := explore(*exploreSpec, timeOptions.ScheduleSeed, seed, *runs, timeOptions.RecordPath); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if clock, err = timeOptions.New(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Create a channel for reports and a wait group for the printer
+	reportChan := make(chan TracesSequenceType, NrOfProcesses)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go printer(reportChan, &wg)
+
+	processTasks, err := simulate(seed)
+
+	// Send the reports of the finished process tasks
+	for _, pt := range processTasks {
+		pt.tracesMutex.Lock()
+		reportChan <- pt.traces
+		pt.tracesMutex.Unlock()
+	}
+
+	close(reportChan)
+	wg.Wait()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// String representation of ProcessState
+func (ps ProcessState) String() string {
+	switch ps {
+
+	case LocalSection:
+		return "LOCAL_SECTION"
+	case EntryProtocol:
+		return "ENTRY_PROTOCOL"
+	case CriticalSection:
+		return "CRITICAL_SECTION"
+	case ExitProtocol:
+		return "EXIT_PROTOCOL"
+	default:
+		return "UNKNOWN_STATE"
+	}
+}
@@ -0,0 +1,74 @@
+package scenario
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dawid831/ParallelProgramming/board"
+)
+
+// Positions is a flag of space separated cells, each "x,y" or, on a
+// torus3d board, "x,y,z".
+type Positions []board.Position
+
+func (p *Positions) String() string {
+	if p == nil {
+		return ""
+	}
+	cells := make([]string, len(*p))
+	for i, pos := range *p {
+		cells[i] = fmt.Sprintf("%d,%d", pos.X, pos.Y)
+		if pos.Z != 0 {
+			cells[i] += fmt.Sprintf(",%d", pos.Z)
+		}
+	}
+	return strings.Join(cells, " ")
+}
+
+func (p *Positions) Set(s string) error {
+	*p = nil
+	for _, cell := range strings.Fields(s) {
+		parts := strings.Split(cell, ",")
+		if len(parts) < 2 || len(parts) > 3 {
+			return fmt.Errorf("cell %q: want x,y or x,y,z", cell)
+		}
+		var coords [3]int
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("cell %q: bad coordinate %q", cell, part)
+			}
+			coords[i] = n
+		}
+		*p = append(*p, board.Position{X: coords[0], Y: coords[1], Z: coords[2]})
+	}
+	return nil
+}
+
+// On checks that every cell is on b and not a wall.
+func (p Positions) On(b *board.Board) error {
+	for _, pos := range p {
+		if !b.Contains(pos) || b.Wall(pos) {
+			return fmt.Errorf("cell %d,%d,%d is not a free cell of the board", pos.X, pos.Y, pos.Z)
+		}
+	}
+	return nil
+}
+
+// Start is where entity i starts: the i-th cell of p or, past its end, the
+// start the map of b gives symbol.
+func (p Positions) Start(b *board.Board, i int, symbol rune) (board.Position, bool) {
+	if i < len(p) {
+		return p[i], true
+	}
+	return b.Start(symbol)
+}
+
+// RegisterStarts adds the -starts flag to the default flag set.
+func RegisterStarts() *Positions {
+	p := &Positions{}
+	flag.Var(p, "starts", "space separated x,y cells the travelers start on, in turn; the rest start where the map or the scenario puts them")
+	return p
+}
@@ -0,0 +1,361 @@
+// Package rwmonitor is lista4 zadanie4: readers and writers sharing a
+// reading room through a monitor with condition queues.
+package rwmonitor
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/replay"
+	"github.com/dawid831/ParallelProgramming/scenario"
+	"github.com/dawid831/ParallelProgramming/simtime"
+)
+
+// Parameters, the former constants; Main sets them from its flags.
+var (
+	NumReaders    = 10
+	NumWriters    = 5
+	NrOfProcesses = NumReaders + NumWriters
+	MinSteps      = 20
+	MaxSteps      = 40
+	MinDelay      = 10 * time.Millisecond
+	MaxDelay      = 50 * time.Millisecond
+)
+
+// out receives the traces, stdout or the file of -out.
+var out io.Writer = os.Stdout
+
+type ProcessState int
+
+const (
+	LocalSection ProcessState = iota
+	Start
+	ReadingRoom
+	Stop
+)
+
+func (s ProcessState) String() string {
+	return [...]string{"LOCAL_SECTION", "START", "READING_ROOM", "STOP"}[s]
+}
+
+type Process struct {
+	ID           int
+	Symbol       rune
+	State        ProcessState
+	Steps        int
+	random       *rand.Rand
+	stateChanges []Trace
+}
+
+type Trace struct {
+	Timestamp time.Duration
+	ID        int
+	State     ProcessState
+	Symbol    rune
+}
+
+func (p *Process) recordState(state ProcessState) {
+	stamp := clock.Now()
+	p.stateChanges = append(p.stateChanges, Trace{
+		Timestamp: stamp,
+		ID:        p.ID,
+		State:     state,
+		Symbol:    p.Symbol,
+	})
+	p.State = state
+}
+
+func (p *Process) randomDelay() {
+	clock.Sleep(MinDelay + time.Duration(p.random.Int63n(int64(MaxDelay-MinDelay)+1)))
+}
+
+func printer(processes []*Process) {
+	defer printerWG.Done()
+
+	// Wait for all processes to finish
+	wg.Wait()
+
+	// Collect all state changes
+	var allChanges []Trace
+	for _, p := range processes {
+		allChanges = append(allChanges, p.stateChanges...)
+	}
+
+	for _, change := range allChanges {
+		fmt.Fprintf(out, "%.9f %d %d %d %c\n",
+			change.Timestamp.Seconds(),
+			change.ID,
+			change.ID,         // X position (same as ID)
+			int(change.State), // Y position
+			change.Symbol)
+	}
+
+	fmt.Fprintf(out, "-1 %d %d %d ", NrOfProcesses, NrOfProcesses, 4)
+	for state := LocalSection; state <= Stop; state++ {
+		fmt.Fprintf(out, "%s;", state)
+	}
+	fmt.Fprintln(out)
+}
+
+// ########### MONITOR ###########
+type request struct {
+	response chan bool
+}
+
+type Monitor struct {
+	enterChan chan struct{}
+	condVars  map[string]*Condition
+}
+
+type Condition struct {
+	queue   []*request
+	monitor *Monitor
+}
+
+func NewMonitor() *Monitor {
+	m := &Monitor{
+		enterChan: make(chan struct{}, 1),
+		condVars:  make(map[string]*Condition),
+	}
+	m.enterChan <- struct{}{}
+	return m
+}
+
+func (m *Monitor) Enter() {
+	simtime.Receive(clock, m.enterChan)
+}
+
+func (m *Monitor) Leave() {
+	m.enterChan <- struct{}{}
+}
+
+func (m *Monitor) NewCondition(name string) *Condition {
+	if _, exists := m.condVars[name]; !exists {
+		m.condVars[name] = &Condition{
+			queue:   make([]*request, 0),
+			monitor: m,
+		}
+	}
+	return m.condVars[name]
+}
+
+func (c *Condition) Wait() {
+	// The signaller does not wait for the response to be taken
+	req := request{response: make(chan bool, 1)}
+	c.queue = append(c.queue, &req)
+	c.monitor.Leave()
+	simtime.Receive(clock, req.response)
+	c.monitor.Enter()
+}
+
+func (c *Condition) Signal() {
+	c.monitor.Leave()
+	if len(c.queue) > 0 {
+		first := c.queue[0]
+		c.queue = c.queue[1:]
+		first.response <- true
+	}
+}
+
+func (c *Condition) QueueLength() int {
+	return len(c.queue)
+}
+
+// ########### RW ###########
+
+type RWMonitor struct {
+	monitor      *Monitor
+	okToRead     *Condition
+	okToWrite    *Condition
+	readersCount int
+	writing      bool
+	waitWriters  int
+}
+
+func NewRWMonitor() *RWMonitor {
+	m := NewMonitor()
+	return &RWMonitor{
+		monitor:      m,
+		okToRead:     m.NewCondition("okToRead"),
+		okToWrite:    m.NewCondition("okToWrite"),
+		readersCount: 0,
+		writing:      false,
+		waitWriters:  0,
+	}
+}
+
+func (rw *RWMonitor) StartRead() {
+	rw.monitor.Enter()
+
+	if rw.writing || rw.waitWriters > 0 {
+		rw.okToRead.Wait()
+	}
+
+	rw.readersCount++
+	rw.okToRead.Signal()
+}
+
+func (rw *RWMonitor) StopRead() {
+	rw.monitor.Enter()
+
+	rw.readersCount--
+	if rw.readersCount == 0 {
+		rw.okToWrite.Signal()
+	} else {
+		rw.monitor.Leave()
+	}
+}
+
+func (rw *RWMonitor) StartWrite() {
+	rw.monitor.Enter()
+	rw.waitWriters++
+
+	if rw.readersCount > 0 || rw.writing {
+		rw.okToWrite.Wait()
+	}
+
+	rw.waitWriters--
+	rw.writing = true
+	rw.monitor.Leave()
+}
+
+func (rw *RWMonitor) StopWrite() {
+	rw.monitor.Enter()
+
+	rw.writing = false
+	if rw.okToRead.QueueLength() > 0 {
+		rw.okToRead.Signal()
+	} else {
+		rw.okToWrite.Signal()
+	}
+}
+
+func (p *Process) reader(rw *RWMonitor) {
+	defer wg.Done()
+
+	p.recordState(LocalSection)
+	for step := 0; step < p.Steps/4-1; step++ {
+		// Local Section
+		p.randomDelay()
+
+		p.recordState(Start)
+		rw.StartRead()
+
+		p.recordState(ReadingRoom)
+		p.randomDelay()
+
+		p.recordState(Stop)
+		rw.StopRead()
+
+		p.recordState(LocalSection)
+	}
+}
+
+func (p *Process) writer(rw *RWMonitor) {
+	defer wg.Done()
+
+	p.recordState(LocalSection)
+	for step := 0; step < p.Steps/4-1; step++ {
+		// Local Section
+		p.randomDelay()
+
+		p.recordState(Start)
+		rw.StartWrite()
+
+		p.recordState(ReadingRoom)
+		p.randomDelay()
+
+		p.recordState(Stop)
+		rw.StopWrite()
+
+		p.recordState(LocalSection)
+	}
+}
+
+var (
+	clock simtime.Clock
+
+	wg        sync.WaitGroup
+	printerWG sync.WaitGroup
+)
+
+// Main runs the simulation with the parameters given on the command line.
+func Main() {
+	flag.IntVar(&NumReaders, "readers", NumReaders, "number of readers")
+	flag.IntVar(&NumWriters, "writers", NumWriters, "number of writers")
+	scenario.Steps(&MinSteps, &MaxSteps)
+	scenario.Delays(&MinDelay, &MaxDelay)
+	output := scenario.RegisterOutput()
+	timeOptions := simtime.RegisterFlags()
+	seedOption := scenario.RegisterSeed()
+	flag.Parse()
+	scenario.Check(
+		scenario.AtLeast("readers", NumReaders, 0),
+		scenario.AtLeast("writers", NumWriters, 0),
+		scenario.Range("steps", MinSteps, MaxSteps),
+		scenario.Range("delays", MinDelay, MaxDelay),
+	)
+	NrOfProcesses = NumReaders + NumWriters
+	var err error
+	if out, err = output.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	seed := seedOption.Pick()
+
+	if clock, err = timeOptions.New(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	processes := make([]*Process, NrOfProcesses)
+	for i := 0; i < NumReaders; i++ {
+		p := &Process{
+			ID:     i,
+			Symbol: rune('R'),
+			random: rand.New(rand.NewSource(replay.Mix(seed, i))),
+		}
+		p.Steps = MinSteps + p.random.Intn(MaxSteps-MinSteps+1)
+		processes[i] = p
+	}
+
+	for i := NumReaders; i < NrOfProcesses; i++ {
+		p := &Process{
+			ID:     i,
+			Symbol: rune('W'),
+			random: rand.New(rand.NewSource(replay.Mix(seed, i))),
+		}
+		p.Steps = MinSteps + p.random.Intn(MaxSteps-MinSteps+1)
+		processes[i] = p
+	}
+
+	// Start printer
+	printerWG.Add(1)
+	go printer(processes)
+
+	rw := NewRWMonitor()
+
+	for i := 0; i < NumReaders; i++ {
+		wg.Add(1)
+		p := processes[i]
+		clock.Go(func() { p.reader(rw) })
+	}
+
+	for i := 0; i < NumWriters; i++ {
+		wg.Add(1)
+		p := processes[NumReaders+i]
+		clock.Go(func() { p.writer(rw) })
+	}
+	clock.Start()
+
+	// Wait for all processes to finish
+	wg.Wait()
+
+	// Signal printer to finish
+	printerWG.Wait()
+}
@@ -0,0 +1,107 @@
+// Package scenario holds what the simulations share as subcommands of
+// cmd/sim: the -out, -seed and -starts flags, the checks of the parameters
+// that used to be constants and the scenario files that stand for a whole
+// command line. The simulations themselves are its subpackages, one per
+// assignment, each run by its Main.
+package scenario
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Output is the -out command line flag.
+type Output struct {
+	Path string
+}
+
+// RegisterOutput adds the -out flag to the default flag set.
+func RegisterOutput() *Output {
+	o := &Output{}
+	flag.StringVar(&o.Path, "out", "", "write the traces to `file` instead of stdout")
+	return o
+}
+
+// Open returns where the traces go. A file is not buffered, so whatever a
+// run wrote survives it ending in os.Exit.
+func (o *Output) Open() (io.Writer, error) {
+	if o.Path == "" {
+		return os.Stdout, nil
+	}
+	return os.Create(o.Path)
+}
+
+// Range checks that the parameters from lo to hi, named name, are a range
+// of values that are not negative.
+func Range[T ~int | ~int64](name string, lo, hi T) error {
+	if lo < 0 || hi < lo {
+		return fmt.Errorf("%s from %v to %v: want 0 <= min <= max", name, lo, hi)
+	}
+	return nil
+}
+
+// AtLeast checks that parameter name is n >= least.
+func AtLeast(name string, n, least int) error {
+	if n < least {
+		return fmt.Errorf("%s %d: want at least %d", name, n, least)
+	}
+	return nil
+}
+
+// AtMost checks that parameter name is n <= most.
+func AtMost(name string, n, most int) error {
+	if n > most {
+		return fmt.Errorf("%s %d: want at most %d", name, n, most)
+	}
+	return nil
+}
+
+// Check writes the first error of errs to stderr and exits, if there is
+// one.
+func Check(errs ...error) {
+	for _, err := range errs {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+}
+
+// Delays registers the -min-delay and -max-delay flags over min and max,
+// which hold the defaults.
+func Delays(min, max *time.Duration) {
+	flag.DurationVar(min, "min-delay", *min, "shortest delay of a step")
+	flag.DurationVar(max, "max-delay", *max, "longest delay of a step")
+}
+
+// Steps registers the -min-steps and -max-steps flags the same way.
+func Steps(min, max *int) {
+	flag.IntVar(min, "min-steps", *min, "fewest steps of a task")
+	flag.IntVar(max, "max-steps", *max, "most steps of a task")
+}
+
+// Seed is the -seed command line flag of the scenarios that draw from a
+// plain random source rather than a decision log.
+type Seed struct {
+	Value int64
+}
+
+// RegisterSeed adds the -seed flag to the default flag set.
+func RegisterSeed() *Seed {
+	s := &Seed{}
+	flag.Int64Var(&s.Value, "seed", 0, "seed of the delays and step counts of the processes (0 picks one from the clock)")
+	return s
+}
+
+// Pick returns the seed of the run and writes it to stderr, so the run can
+// be repeated with -seed.
+func (s *Seed) Pick() int64 {
+	if s.Value == 0 {
+		s.Value = time.Now().UnixNano()
+	}
+	fmt.Fprintf(os.Stderr, "seed %d\n", s.Value)
+	return s.Value
+}
@@ -0,0 +1,256 @@
+// Package szymanski is lista4 zadanie2: mutual exclusion of many processes
+// by Szymanski's algorithm, whose entry protocol has four stages.
+package szymanski
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/replay"
+	"github.com/dawid831/ParallelProgramming/scenario"
+	"github.com/dawid831/ParallelProgramming/simtime"
+)
+
+// Parameters, the former constants; Main sets them from its flags.
+var (
+	NrOfProcesses = 15
+	MinSteps      = 50
+	MaxSteps      = 100
+	MinDelay      = 10 * time.Millisecond
+	MaxDelay      = 50 * time.Millisecond
+)
+
+// out receives the traces, stdout or the file of -out.
+var out io.Writer = os.Stdout
+
+type ProcessState int
+
+const (
+	LocalSection ProcessState = iota
+	EntryProtocol_1
+	EntryProtocol_2
+	EntryProtocol_3
+	EntryProtocol_4
+	CriticalSection
+	ExitProtocol
+)
+
+func (s ProcessState) String() string {
+	return [...]string{"LOCAL_SECTION", "ENTRY_PROTOCOL_1", "ENTRY_PROTOCOL_2", "ENTRY_PROTOCOL_3", "ENTRY_PROTOCOL_4", "CRITICAL_SECTION", "EXIT_PROTOCOL"}[s]
+}
+
+type Process struct {
+	ID           int
+	Symbol       rune
+	State        ProcessState
+	Steps        int
+	MyMaxTicket  int32
+	random       *rand.Rand
+	stateChanges []Trace
+}
+
+type Trace struct {
+	Timestamp time.Duration
+	ID        int
+	State     ProcessState
+	Symbol    rune
+}
+
+var (
+	flags []int32
+	clock simtime.Clock
+
+	wg        sync.WaitGroup
+	printerWG sync.WaitGroup
+)
+
+// Main runs the simulation with the parameters given on the command line.
+func Main() {
+	flag.IntVar(&NrOfProcesses, "processes", NrOfProcesses, "number of processes")
+	scenario.Steps(&MinSteps, &MaxSteps)
+	scenario.Delays(&MinDelay, &MaxDelay)
+	output := scenario.RegisterOutput()
+	timeOptions := simtime.RegisterFlags()
+	seedOption := scenario.RegisterSeed()
+	flag.Parse()
+	scenario.Check(
+		scenario.AtLeast("processes", NrOfProcesses, 1),
+		scenario.Range("steps", MinSteps, MaxSteps),
+		scenario.Range("delays", MinDelay, MaxDelay),
+	)
+	var err error
+	if out, err = output.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	seed := seedOption.Pick()
+
+	// Shared arrays
+	flags = make([]int32, NrOfProcesses)
+	if clock, err = timeOptions.New(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Create processes
+	processes := make([]*Process, NrOfProcesses)
+	for i := 0; i < NrOfProcesses; i++ {
+		p := &Process{
+			ID:     i,
+			Symbol: rune('A' + i),
+			random: rand.New(rand.NewSource(replay.Mix(seed, i))),
+		}
+		p.Steps = MinSteps + p.random.Intn(MaxSteps-MinSteps+1)
+		processes[i] = p
+	}
+
+	// Start printer
+	printerWG.Add(1)
+	go printer(processes)
+
+	// Start processes
+	for _, p := range processes {
+		wg.Add(1)
+		clock.Go(p.Run)
+	}
+	clock.Start()
+
+	// Wait for all processes to finish
+	wg.Wait()
+
+	// Signal printer to finish
+	printerWG.Wait()
+}
+
+func (p *Process) Run() {
+	defer wg.Done()
+	id := p.ID
+
+	p.recordState(LocalSection)
+	for step := 0; step < p.Steps/7-1; step++ {
+		// Local Section
+		p.randomDelay()
+
+		// Entry Protocol
+		atomic.StoreInt32(&flags[id], 1)
+		p.recordState(EntryProtocol_1)
+		clock.Wait(-1, func() bool {
+			for j := 0; j < NrOfProcesses; j++ {
+				if flags[j] > 2 {
+					return false
+				}
+			}
+			return true
+		})
+
+		atomic.StoreInt32(&flags[id], 3)
+		p.recordState(EntryProtocol_3)
+
+		success := false
+		for j := 0; j < NrOfProcesses; j++ {
+			if flags[j] == 1 {
+				success = true
+				break
+			}
+		}
+
+		if success {
+			atomic.StoreInt32(&flags[p.ID], 2)
+			p.recordState(EntryProtocol_2)
+
+			clock.Wait(-1, func() bool {
+				for j := 0; j < NrOfProcesses; j++ {
+					if flags[j] == 4 {
+						return true
+					}
+				}
+				return false
+			})
+		}
+
+		atomic.StoreInt32(&flags[p.ID], 4)
+		p.recordState(EntryProtocol_4)
+
+		clock.Wait(-1, func() bool {
+			for j := 0; j < p.ID; j++ {
+				if flags[j] > 1 {
+					return false
+				}
+			}
+			return true
+		})
+
+		p.recordState(CriticalSection)
+		p.randomDelay()
+
+		// Exit Protocol
+		p.recordState(ExitProtocol)
+
+		for {
+			success := true
+			for j := p.ID + 1; j < NrOfProcesses; j++ {
+				if flags[j] == 2 || flags[j] == 3 {
+					success = false
+					break
+				}
+			}
+
+			if success {
+				break
+			}
+			clock.Sleep(1 * time.Millisecond)
+		}
+
+		atomic.StoreInt32(&flags[p.ID], 0)
+		p.recordState(LocalSection)
+	}
+}
+
+func (p *Process) randomDelay() {
+	clock.Sleep(MinDelay + time.Duration(p.random.Int63n(int64(MaxDelay-MinDelay)+1)))
+}
+
+func (p *Process) recordState(state ProcessState) {
+	stamp := clock.Now()
+	p.stateChanges = append(p.stateChanges, Trace{
+		Timestamp: stamp,
+		ID:        p.ID,
+		State:     state,
+		Symbol:    p.Symbol,
+	})
+	p.State = state
+}
+
+func printer(processes []*Process) {
+	defer printerWG.Done()
+
+	// Wait for all processes to finish
+	wg.Wait()
+
+	// Collect all state changes
+	var allChanges []Trace
+	for _, p := range processes {
+		allChanges = append(allChanges, p.stateChanges...)
+	}
+
+	for _, change := range allChanges {
+		fmt.Fprintf(out, "%.9f %d %d %d %c\n",
+			change.Timestamp.Seconds(),
+			change.ID,
+			change.ID,         // X position (same as ID)
+			int(change.State), // Y position
+			change.Symbol)
+	}
+
+	fmt.Fprintf(out, "-1 %d %d %d ", NrOfProcesses, NrOfProcesses, 7)
+	for state := LocalSection; state <= ExitProtocol; state++ {
+		fmt.Fprintf(out, "%s;", state)
+	}
+	fmt.Fprintln(out)
+}
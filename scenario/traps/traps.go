@@ -0,0 +1,1429 @@
+// Package traps is lista2 zadanie4: the wild tenant board of wildtenants
+// with traps that catch whoever steps on them, moves run as two-phase
+// commits over the cell actors, and snapshots of the running board.
+package traps
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/dawid831/ParallelProgramming/actor"
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/checker"
+	"github.com/dawid831/ParallelProgramming/replay"
+	"github.com/dawid831/ParallelProgramming/scenario"
+	"github.com/dawid831/ParallelProgramming/shard"
+	"github.com/dawid831/ParallelProgramming/snapshot"
+	"github.com/dawid831/ParallelProgramming/strategy"
+)
+
+// Parameters, the former constants; Main sets them from its flags.
+var (
+	NrOfTravelers      = 15
+	NrOfWildTenants    = 10
+	MinSteps           = 10
+	MaxSteps           = 100
+	MinDelay           = 10 * time.Millisecond
+	MaxDelay           = 50 * time.Millisecond
+	WildTenantLifetime = 500 * time.Millisecond
+	BoardWidth         = 15
+	BoardHeight        = 15
+	ShardSize          = 1
+	DEBUG              = false
+	TrapsCount         = 10
+)
+
+// out receives the traces, stdout or the file of -out.
+var out io.Writer = os.Stdout
+
+type Player struct {
+	ID       int
+	Symbol   rune
+	Position board.Position
+	Wild     bool
+	// pushed brings a wild tenant the latest place a traveler pushed it
+	// to. Only the tenant's own task writes its Position and Symbol.
+	pushed chan push
+}
+
+// push is where a traveler pushed a wild tenant, and whether a trap caught
+// it there.
+type push struct {
+	position board.Position
+	trapped  bool
+}
+
+// push tells the task of wild tenant p where it stands now, replacing a
+// push it has not taken yet. A tenant is pushed by one traveler at a time,
+// the one holding the cell it stands on.
+func (p *Player) push(position board.Position, trapped bool) {
+	select {
+	case <-p.pushed:
+	default:
+	}
+	p.pushed <- push{position: position, trapped: trapped}
+}
+
+type AtomicCounter struct {
+	count int
+	mu    sync.Mutex
+}
+
+func (c *AtomicCounter) Increment() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count++
+}
+
+func (c *AtomicCounter) Decrement() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count--
+}
+
+func (c *AtomicCounter) GetCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+type Trap struct {
+	Position board.Position
+	ID       int
+}
+
+// Messages understood by a Cell. A request that expects an answer carries
+// its own reply channel. Handlers only touch the cell's own fields; whatever
+// they need about players arrives as a copy inside the message.
+type cellMessage interface {
+	cellMessage()
+}
+
+// envelope is a message together with the task that sent it. Every task
+// has its own FIFO channel to each cell, which the snapshot needs to tell
+// apart.
+type envelope struct {
+	from int
+	msg  cellMessage
+}
+
+// Senders that are not tasks.
+const (
+	// mainTask sets the board up before any task starts.
+	mainTask = -1
+	// initiator starts snapshots.
+	initiator = -2
+)
+
+type lockRequest struct{ reply chan bool }
+
+type unlockRequest struct{}
+
+type occupyRequest struct {
+	id   int
+	wild bool
+}
+
+type clearRequest struct{}
+
+type queryRequest struct{ reply chan cellState }
+
+// prepareRequest asks the cell to vote on op and, voting yes, to reserve
+// itself for transaction txn.
+type prepareRequest struct {
+	txn   int64
+	op    cellOp
+	reply chan vote
+}
+
+// commitRequest applies the prepared op of txn and stores record, if any.
+type commitRequest struct {
+	txn    int64
+	record *board.Trace
+}
+
+// abortRequest drops the reservation of txn; the cell stays as it was.
+type abortRequest struct{ txn int64 }
+
+type addTrapRequest struct{ trap Trap }
+
+// traceRequest stores trace, stamped by the sender.
+type traceRequest struct{ trace board.Trace }
+
+type exportRequest struct{ reply chan []board.Trace }
+
+// markerRequest is the Chandy–Lamport marker of snapshot.
+type markerRequest struct{ snapshot *Snapshot }
+
+func (lockRequest) cellMessage()    {}
+func (unlockRequest) cellMessage()  {}
+func (occupyRequest) cellMessage()  {}
+func (clearRequest) cellMessage()   {}
+func (queryRequest) cellMessage()   {}
+func (prepareRequest) cellMessage() {}
+func (commitRequest) cellMessage()  {}
+func (abortRequest) cellMessage()   {}
+func (addTrapRequest) cellMessage() {}
+func (traceRequest) cellMessage()   {}
+func (exportRequest) cellMessage()  {}
+func (markerRequest) cellMessage()  {}
+
+// opKind is the part a cell plays in a move.
+type opKind int
+
+const (
+	// enterOp: a traveler takes the cell, pushing out any wild tenant.
+	enterOp opKind = iota
+	// pushOp: a pushed wild tenant lands on the cell.
+	pushOp
+	// leaveOp: the traveler standing on the cell moves off it.
+	leaveOp
+)
+
+type cellOp struct {
+	kind opKind
+	id   int
+}
+
+// vote is a cell's answer to prepare, with its state at that moment.
+type vote struct {
+	ok    bool
+	state cellState
+}
+
+// cellState is what a query tells about a cell.
+type cellState struct {
+	locked   bool
+	occupied bool
+	wild     bool
+	trapped  bool
+	occupant int
+	trap     Trap
+}
+
+// cellData is the state of a cell a snapshot records.
+type cellData struct {
+	locked   bool
+	occupied bool
+	wall     bool
+	wild     bool
+	trapped  bool
+	occupant int
+	trap     Trap
+	// pending is the op reserved by transaction txn, nil when none is.
+	pending *cellOp
+	txn     int64
+}
+
+type Cell struct {
+	regions *shard.Board[envelope]
+	cellData
+	position board.Position
+	traces   []board.Trace
+	// recording is the snapshot in progress, nil between snapshots.
+	recording *recording
+}
+
+// recording is a cell's part of a snapshot: its state when the first marker
+// came, updated by every message that arrives on a channel whose marker has
+// not come yet.
+type recording struct {
+	snapshot *Snapshot
+	data     cellData
+	open     map[int]bool
+}
+
+// NewCell makes the cell at position, run by its region of regions. Once
+// the system of the regions stops, every command to the cell fails with
+// actor.ErrStopped.
+func NewCell(regions *shard.Board[envelope], position board.Position, data cellData) *Cell {
+	return &Cell{regions: regions, cellData: data, position: position}
+}
+
+func (c *Cell) handle(e envelope) {
+	if DEBUG {
+		fmt.Printf("Cell starts %T\n", e.msg)
+	}
+	if r := c.recording; r != nil && r.open[e.from] && r.data.update(e.msg) {
+		r.snapshot.inTransit.Add(1)
+	}
+	switch m := e.msg.(type) {
+	case lockRequest:
+		ok := !c.locked && !c.wall && c.pending == nil
+		c.update(m)
+		m.reply <- ok
+	case queryRequest:
+		m.reply <- c.state()
+	case prepareRequest:
+		ok := c.pending == nil && c.accepts(m.op)
+		c.update(m)
+		m.reply <- vote{ok: ok, state: c.state()}
+	case commitRequest:
+		if c.pending == nil || c.txn != m.txn {
+			panic(fmt.Sprintf("cell: commit of transaction %d that was not prepared here", m.txn))
+		}
+		c.update(m)
+		if m.record != nil {
+			c.traces = append(c.traces, *m.record)
+		}
+	case traceRequest:
+		c.traces = append(c.traces, m.trace)
+	case exportRequest:
+		traces := make([]board.Trace, len(c.traces))
+		copy(traces, c.traces)
+		m.reply <- traces
+	case markerRequest:
+		c.marker(e.from, m.snapshot)
+	default:
+		if !c.update(e.msg) {
+			panic(fmt.Sprintf("cell: unknown message %T", e.msg))
+		}
+	}
+	if DEBUG {
+		fmt.Printf("Cell ends\n")
+	}
+}
+
+// update applies what msg changes in d and reports whether it is such a
+// message. Replies are left to the caller, so a snapshot can replay
+// messages on its copy of the state.
+func (d *cellData) update(msg cellMessage) bool {
+	switch m := msg.(type) {
+	case lockRequest:
+		if !d.locked && !d.wall && d.pending == nil {
+			d.locked = true
+		}
+	case unlockRequest:
+		d.locked = false
+	case occupyRequest:
+		d.occupant = m.id
+		d.wild = m.wild
+		d.occupied = true
+	case clearRequest:
+		d.occupied = false
+	case prepareRequest:
+		if d.pending == nil && d.accepts(m.op) {
+			op := m.op
+			d.pending = &op
+			d.txn = m.txn
+		}
+	case commitRequest:
+		if d.pending != nil && d.txn == m.txn {
+			d.apply(*d.pending)
+			d.pending = nil
+		}
+	case abortRequest:
+		if d.pending != nil && d.txn == m.txn {
+			d.pending = nil
+		}
+	case addTrapRequest:
+		d.trapped = true
+		d.trap = m.trap
+	default:
+		return false
+	}
+	return true
+}
+
+// marker records the cell on the first marker of s and closes the channel
+// from the sender. Once every channel is closed the record goes to s.
+func (c *Cell) marker(from int, s *Snapshot) {
+	r := c.recording
+	if r == nil {
+		r = &recording{snapshot: s, data: c.cellData, open: make(map[int]bool)}
+		for _, id := range s.senders {
+			r.open[id] = true
+		}
+		c.recording = r
+	}
+	delete(r.open, from)
+	if len(r.open) == 0 {
+		s.cells <- c.record(r.data)
+		c.recording = nil
+	}
+}
+
+func (c *Cell) record(d cellData) snapshot.Cell {
+	rec := snapshot.Cell{
+		X:       c.position.X,
+		Y:       c.position.Y,
+		Locked:  d.locked,
+		Trapped: d.trapped,
+	}
+	if d.occupied {
+		rec.Occupied, rec.Wild, rec.Occupant = true, d.wild, d.occupant
+	}
+	if d.trapped {
+		rec.TrapID = d.trap.ID
+	}
+	return rec
+}
+
+func (c *Cell) state() cellState {
+	return cellState{
+		locked:   c.locked || c.pending != nil,
+		occupied: c.occupied,
+		wild:     c.wild,
+		trapped:  c.trapped,
+		occupant: c.occupant,
+		trap:     c.trap,
+	}
+}
+
+// accepts reports whether op can be applied to the cell as it is now.
+func (d *cellData) accepts(op cellOp) bool {
+	switch op.kind {
+	case enterOp:
+		return !d.wall && !d.locked && (!d.occupied || d.wild)
+	case pushOp:
+		return !d.wall && !d.locked && !d.occupied
+	case leaveOp:
+		return d.occupied && !d.wild && d.occupant == op.id
+	}
+	return false
+}
+
+func (d *cellData) apply(op cellOp) {
+	switch op.kind {
+	case enterOp:
+		d.occupant, d.wild, d.occupied, d.locked = op.id, false, true, true
+	case pushOp:
+		d.occupant, d.wild, d.occupied = op.id, true, true
+	case leaveOp:
+		d.occupied, d.locked = false, false
+	}
+}
+
+func (c *Cell) send(from int, msg cellMessage) error {
+	return c.regions.Send(c.position, envelope{from: from, msg: msg})
+}
+
+func (c *Cell) query(from int) (cellState, error) {
+	reply := make(chan cellState, 1)
+	if err := c.send(from, queryRequest{reply: reply}); err != nil {
+		return cellState{}, err
+	}
+	return <-reply, nil
+}
+
+// Lock asks for the cell; the attempt is a scheduling point of the clock.
+func (c *Cell) Lock(from int) bool {
+	grid.Time().Yield()
+	if DEBUG {
+		fmt.Printf("Locking\n")
+	}
+	reply := make(chan bool, 1)
+	if c.send(from, lockRequest{reply: reply}) != nil {
+		return false
+	}
+	return <-reply
+}
+
+func (c *Cell) AddTrap(from int, ID int, X int, Y int) error {
+	return c.send(from, addTrapRequest{trap: Trap{
+		Position: board.Position{X: X, Y: Y},
+		ID:       ID,
+	}})
+}
+
+func (c *Cell) CheckTrap(from int) bool {
+	state, err := c.query(from)
+	return err == nil && state.trapped
+}
+
+func (c *Cell) Unlock(from int) error {
+	if DEBUG {
+		fmt.Printf("Unlocking\n")
+	}
+	return c.send(from, unlockRequest{})
+}
+
+func (c *Cell) IsLocked(from int) bool {
+	if DEBUG {
+		fmt.Printf("IsLocked\n")
+	}
+	// A stopped cell can no longer be entered.
+	state, err := c.query(from)
+	return err != nil || state.locked
+}
+
+func (c *Cell) Occupy(from int, mover *Player) error {
+	if DEBUG {
+		fmt.Printf("Occupy\n")
+	}
+	return c.send(from, occupyRequest{id: mover.ID, wild: mover.Wild})
+}
+
+func (c *Cell) IsOccupied(from int) bool {
+	if DEBUG {
+		fmt.Printf("IsOccupied\n")
+	}
+	state, err := c.query(from)
+	return err != nil || state.occupied
+}
+
+func (c *Cell) GetOccupant(from int) int {
+	if DEBUG {
+		fmt.Printf("GetOccupant\n")
+	}
+	state, err := c.query(from)
+	if err != nil {
+		return -1
+	}
+	return state.occupant
+}
+
+func (c *Cell) Clear(from int) error {
+	if DEBUG {
+		fmt.Printf("Clear\n")
+	}
+	return c.send(from, clearRequest{})
+}
+
+// storeTrace records the occupant, whose current state the caller passes
+// in. The trace is stamped here, in the caller's task.
+func (c *Cell) storeTrace(from int, occupant *Player) error {
+	if DEBUG {
+		fmt.Printf("storeTrace\n")
+	}
+	trace := grid.NewTrace(occupant.ID, occupant.Position, occupant.Symbol)
+	return c.send(from, traceRequest{trace: trace})
+}
+
+// storeTrap records the trap of a cell that is empty again.
+func (c *Cell) storeTrap(from int) error {
+	if DEBUG {
+		fmt.Printf("storeTrap\n")
+	}
+	state, err := c.query(from)
+	if err != nil || !state.trapped {
+		return err
+	}
+	trace := grid.NewTrace(state.trap.ID, state.trap.Position, '#')
+	return c.send(from, traceRequest{trace: trace})
+}
+
+func (c *Cell) ExportTraces(from int) []board.Trace {
+	if DEBUG {
+		fmt.Printf("ExportTraces\n")
+	}
+	reply := make(chan []board.Trace, 1)
+	if c.send(from, exportRequest{reply: reply}) != nil {
+		return nil
+	}
+	return <-reply
+}
+
+var lastTxn atomic.Int64
+
+// Transaction runs a move over several cells as a two-phase commit. Each
+// cell votes on its part in Prepare and stays reserved until Commit applies
+// every part or Abort drops them all, leaving every cell as it was.
+type Transaction struct {
+	id       int64
+	from     int
+	prepared []*Cell
+}
+
+// Begin starts a transaction run by task from.
+func Begin(from int) *Transaction {
+	return &Transaction{id: lastTxn.Add(1), from: from}
+}
+
+// Prepare asks c to reserve itself for op. A cell voting no takes no part
+// in the transaction. Like Lock, it is a scheduling point of the clock.
+func (t *Transaction) Prepare(c *Cell, op cellOp) (cellState, bool) {
+	grid.Time().Yield()
+	reply := make(chan vote, 1)
+	if c.send(t.from, prepareRequest{txn: t.id, op: op, reply: reply}) != nil {
+		return cellState{}, false
+	}
+	v := <-reply
+	if v.ok {
+		t.prepared = append(t.prepared, c)
+	}
+	return v.state, v.ok
+}
+
+// Commit applies every prepared part. records are traces stamped once all
+// cells voted yes; each is stored by its cell together with the commit.
+// Cells are only stopped after every traveler finished, so no commit can
+// be refused halfway.
+func (t *Transaction) Commit(records map[*Cell]board.Trace) {
+	for _, c := range t.prepared {
+		req := commitRequest{txn: t.id}
+		if record, ok := records[c]; ok {
+			req.record = &record
+		}
+		c.send(t.from, req)
+	}
+	t.prepared = nil
+}
+
+func (t *Transaction) Abort() {
+	for _, c := range t.prepared {
+		c.send(t.from, abortRequest{txn: t.id})
+	}
+	t.prepared = nil
+}
+
+// Snapshot is a Chandy–Lamport snapshot in progress. Tasks talk to cells
+// over one FIFO channel each way; a cell only ever answers a request of the
+// task it came from, and a task records itself between steps, when no
+// answer is outstanding, so only the task-to-cell channels carry state.
+type Snapshot struct {
+	// senders are the tasks whose markers every cell waits for.
+	senders   []int
+	takenAt   time.Duration
+	cells     chan snapshot.Cell
+	inTransit atomic.Int64
+	mu        sync.Mutex
+	tasks     []snapshot.Task
+}
+
+// record keeps the state of a task and sends its marker to every cell.
+func (s *Snapshot) record(from int, task snapshot.Task) {
+	s.mu.Lock()
+	s.tasks = append(s.tasks, task)
+	s.mu.Unlock()
+	for x := range cells {
+		for y := range cells[x] {
+			cells[x][y].send(from, markerRequest{snapshot: s})
+		}
+	}
+}
+
+// Tasks taking part in snapshots. A running task has a marker channel; one
+// that has finished leaves the state it ended in.
+var tasks = struct {
+	mu       sync.Mutex
+	markers  map[int]chan *Snapshot
+	finished map[int]snapshot.Task
+}{
+	markers:  make(map[int]chan *Snapshot),
+	finished: make(map[int]snapshot.Task),
+}
+
+// joinSnapshots registers task id. The task must poll the returned channel
+// between steps and record itself on every snapshot it receives.
+func joinSnapshots(id int) <-chan *Snapshot {
+	tasks.mu.Lock()
+	defer tasks.mu.Unlock()
+	marker := make(chan *Snapshot, 1)
+	tasks.markers[id] = marker
+	return marker
+}
+
+// leaveSnapshots unregisters task id, which finished in state last, and
+// answers a snapshot that reached it too late.
+func leaveSnapshots(id int, last snapshot.Task) {
+	tasks.mu.Lock()
+	marker := tasks.markers[id]
+	delete(tasks.markers, id)
+	tasks.finished[id] = last
+	tasks.mu.Unlock()
+	select {
+	case s := <-marker:
+		s.record(id, last)
+	default:
+	}
+}
+
+// TakeSnapshot records the board while tasks keep running. Only one
+// snapshot may be in progress at a time. It gives up with actor.ErrStopped
+// once done is closed.
+func TakeSnapshot(seed int64, done <-chan struct{}) (*snapshot.State, error) {
+	s := &Snapshot{
+		takenAt: grid.Elapsed(),
+		cells:   make(chan snapshot.Cell, grid.Width*grid.Height),
+	}
+	// s is complete before the first task sees it
+	tasks.mu.Lock()
+	for id := range tasks.markers {
+		s.senders = append(s.senders, id)
+	}
+	s.senders = append(s.senders, initiator)
+	for _, task := range tasks.finished {
+		s.tasks = append(s.tasks, task)
+	}
+	for _, marker := range tasks.markers {
+		marker <- s
+	}
+	tasks.mu.Unlock()
+
+	for x := range cells {
+		for y := range cells[x] {
+			if err := cells[x][y].send(initiator, markerRequest{snapshot: s}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	state := snapshot.New(grid, seed)
+	state.TakenAt = s.takenAt
+	// A cell is done once every task has sent its marker, so all tasks
+	// have been recorded by then.
+	for range grid.Width * grid.Height {
+		select {
+		case cell := <-s.cells:
+			state.Cells = append(state.Cells, cell)
+		case <-done:
+			return nil, actor.ErrStopped
+		}
+	}
+	sort.Slice(state.Cells, func(i, j int) bool {
+		a, b := state.Cells[i], state.Cells[j]
+		return a.Y < b.Y || a.Y == b.Y && a.X < b.X
+	})
+	s.mu.Lock()
+	state.Tasks = s.tasks
+	s.mu.Unlock()
+	state.InTransit = int(s.inTransit.Load())
+	return state, nil
+}
+
+// moveTraveler moves p onto target in one transaction over every cell the
+// move touches: the cell p leaves, target and, when a wild tenant stands on
+// target, the free neighbour the tenant is pushed to. Either all of them
+// change or none does. The traces of the move are stamped while all cells
+// are still reserved; the trace of p is returned for the caller to keep.
+func moveTraveler(p *Player, target board.Position, dice *replay.Dice) (board.Trace, bool) {
+	tx := Begin(p.ID)
+	from := cells[p.Position.X][p.Position.Y]
+	to := cells[target.X][target.Y]
+
+	state, ok := tx.Prepare(to, cellOp{kind: enterOp, id: p.ID})
+	if !ok {
+		return board.Trace{}, false
+	}
+	var tenant *Player
+	var landing board.Position
+	var trapped bool
+	if state.occupied && state.wild {
+		tenant = players[state.occupant]
+		dirs := append([]board.Direction(nil), grid.Directions()...)
+		dice.Shuffle(len(dirs), func(i, j int) {
+			dirs[i], dirs[j] = dirs[j], dirs[i]
+		})
+		pushed := false
+		for _, dir := range dirs {
+			next, ok := grid.Move(target, dir)
+			if !ok {
+				continue
+			}
+			if state, ok := tx.Prepare(cells[next.X][next.Y], cellOp{kind: pushOp, id: tenant.ID}); ok {
+				landing, trapped, pushed = next, state.trapped, true
+				break
+			}
+		}
+		if !pushed {
+			tx.Abort()
+			return board.Trace{}, false
+		}
+	}
+	if _, ok := tx.Prepare(from, cellOp{kind: leaveOp, id: p.ID}); !ok {
+		tx.Abort()
+		return board.Trace{}, false
+	}
+
+	// All voted yes: stamp the move, then commit it.
+	moved := board.Trace{ID: p.ID, Position: target, Symbol: p.Symbol}
+	if tenant == nil {
+		traces := grid.NewTraces(moved)
+		tx.Commit(nil)
+		p.Position = target
+		return traces[0], true
+	}
+	symbol := tenant.Symbol
+	if trapped {
+		// Trap activated - freeze traveler
+		symbol = '*'
+	}
+	traces := grid.NewTraces(moved, board.Trace{ID: tenant.ID, Position: landing, Symbol: symbol})
+	// Told while landing is still reserved, so the next push comes after
+	tenant.push(landing, trapped)
+	tx.Commit(map[*Cell]board.Trace{cells[landing.X][landing.Y]: traces[1]})
+	p.Position = target
+	if trapped {
+		grid.Time().Sleep(MinDelay)
+
+		// Traveler dies
+		tenant.push(board.Position{X: -1, Y: -1}, true)
+		cells[landing.X][landing.Y].Clear(p.ID)
+		cells[landing.X][landing.Y].storeTrap(p.ID)
+	}
+	return traces[0], true
+}
+
+var (
+	players           []*Player
+	activeTravelers   AtomicCounter
+	activeWildTenants AtomicCounter
+	printerChan       chan []board.Trace
+	wg                sync.WaitGroup
+)
+
+func travelerTask(id int, nrOfSteps int, dice *replay.Dice, spec string, moves strategy.Strategy, marker <-chan *Snapshot) {
+	defer wg.Done()
+	<-startSignal
+
+	traveler := id
+	var traces []board.Trace
+
+	record := func(stepsLeft int) snapshot.Task {
+		task := snapshot.Task{
+			ID:       traveler,
+			Symbol:   string(players[traveler].Symbol),
+			Steps:    stepsLeft,
+			Done:     stepsLeft == 0,
+			Draws:    dice.Draws(),
+			Strategy: spec,
+		}
+		if s, ok := moves.(strategy.Stateful); ok {
+			task.StrategyState = s.State()
+		}
+		return task
+	}
+
+	storeTrace := func() {
+		traces = append(traces, grid.NewTrace(players[traveler].ID, players[traveler].Position, players[traveler].Symbol))
+	}
+
+	makeStep := func() {
+		current := players[traveler].Position
+		view := strategy.NewView(grid, current, func(p board.Position) bool {
+			return cells[p.X][p.Y].IsLocked(traveler)
+		})
+		dir, ok := moves.Next(view)
+		if !ok {
+			return
+		}
+		target, ok := grid.Move(current, dir)
+		if !ok {
+			return
+		}
+		newX, newY := target.X, target.Y
+
+		trace, moved := moveTraveler(players[traveler], target, dice)
+		if !moved {
+			if unicode.IsUpper(players[traveler].Symbol) {
+				players[traveler].Symbol = unicode.ToLower(players[traveler].Symbol)
+			}
+			storeTrace()
+			return
+		}
+		if DEBUG {
+			fmt.Printf("%d moved to %d %d\n", id, newX, newY)
+		}
+		traces = append(traces, trace)
+		// Check for trap activation
+		if cells[newX][newY].CheckTrap(traveler) {
+			// Trap activated - freeze traveler
+			players[traveler].Symbol = unicode.ToLower(players[traveler].Symbol)
+			cells[newX][newY].storeTrace(traveler, players[traveler])
+			grid.Time().Sleep(MinDelay)
+
+			// Traveler dies
+			players[traveler].Position.X = -1
+			players[traveler].Position.Y = -1
+			cells[newX][newY].Clear(traveler)
+			cells[newX][newY].storeTrap(traveler)
+			storeTrace()
+		}
+	}
+
+	for step := 0; step < nrOfSteps; step++ {
+		select {
+		case s := <-marker:
+			s.record(traveler, record(nrOfSteps-step))
+		default:
+		}
+		grid.Time().Sleep(dice.Delay(MinDelay, MaxDelay))
+		makeStep()
+
+		if !unicode.IsUpper(players[traveler].Symbol) {
+			break
+		}
+	}
+
+	if unicode.IsUpper(players[traveler].Symbol) {
+		players[traveler].Symbol = unicode.ToLower(players[traveler].Symbol)
+		storeTrace()
+	}
+
+	leaveSnapshots(traveler, record(0))
+	activeTravelers.Decrement()
+	printerChan <- traces
+}
+
+// wildTenantTask runs wild tenant id. A tenant that is already on the board
+// has life left to live.
+func wildTenantTask(id int, life time.Duration, dice *replay.Dice, marker <-chan *Snapshot) {
+	defer wg.Done()
+	<-startSignal
+
+	wildTenant := id
+	var traces []board.Trace
+	alive := life > 0
+	birthTime := grid.Elapsed() + life - WildTenantLifetime
+	// trapped: a traveler pushed the tenant onto a trap, which takes it
+	trapped := false
+
+	// moved takes the place a traveler pushed the tenant to, if any
+	moved := func() {
+		select {
+		case p := <-players[wildTenant].pushed:
+			players[wildTenant].Position = p.position
+			trapped = p.trapped
+		default:
+		}
+	}
+
+	record := func(done bool) snapshot.Task {
+		symbol := players[wildTenant].Symbol
+		if trapped {
+			symbol = '*'
+		}
+		task := snapshot.Task{
+			ID:     wildTenant,
+			Symbol: string(symbol),
+			Wild:   true,
+			Done:   done,
+			Draws:  dice.Draws(),
+		}
+		if alive && !done {
+			task.Life = max(WildTenantLifetime-(grid.Elapsed()-birthTime), time.Nanosecond)
+		}
+		return task
+	}
+
+	storeTrace := func() {
+		traces = append(traces, grid.NewTrace(players[wildTenant].ID, players[wildTenant].Position, players[wildTenant].Symbol))
+	}
+
+	safeAppear := func() {
+		for attempt := 0; attempt < 10; attempt++ {
+			pos := dice.Position(grid)
+			x, y := pos.X, pos.Y
+
+			if cells[x][y].Lock(wildTenant) {
+				if !cells[x][y].IsOccupied(wildTenant) {
+					if !cells[x][y].CheckTrap(wildTenant) {
+						players[wildTenant].Position.X = x
+						players[wildTenant].Position.Y = y
+						players[wildTenant].Symbol = rune('0' + byte(players[wildTenant].ID%10))
+						cells[x][y].Occupy(wildTenant, players[wildTenant])
+						alive = true
+						trapped = false
+						birthTime = grid.Elapsed()
+						storeTrace()
+						cells[x][y].Unlock(wildTenant)
+						return
+					}
+				}
+				cells[x][y].Unlock(wildTenant)
+			}
+			grid.Time().Sleep(WildTenantLifetime / 10)
+		}
+	}
+
+	safeDisappear := func() {
+		x := players[wildTenant].Position.X
+		y := players[wildTenant].Position.Y
+
+		if x == -1 {
+			alive = false
+		} else if trapped {
+			// the traveler that pushed it there clears the cell
+		} else if cells[x][y].Lock(wildTenant) {
+			if cells[x][y].GetOccupant(wildTenant) == wildTenant {
+				players[wildTenant].Position.X = -1
+				players[wildTenant].Position.Y = -1
+				cells[x][y].Clear(wildTenant)
+				storeTrace()
+				alive = false
+			}
+			cells[x][y].Unlock(wildTenant)
+		}
+	}
+
+	for {
+		moved()
+		select {
+		case s := <-marker:
+			s.record(wildTenant, record(false))
+		default:
+		}
+		if !alive {
+			if DEBUG {
+				fmt.Printf("APPEARING\n")
+			}
+			safeAppear()
+		} else {
+			if grid.Elapsed()-birthTime > WildTenantLifetime {
+				safeDisappear()
+			}
+		}
+
+		if activeTravelers.GetCount() < 1 {
+			break
+		}
+		grid.Time().Sleep(10 * time.Millisecond)
+	}
+
+	leaveSnapshots(wildTenant, record(true))
+	activeWildTenants.Decrement()
+	printerChan <- traces
+}
+
+// storeFinished stores the one trace of player p, whose task had finished in
+// the snapshot the run starts from, where the snapshot left it. A player
+// standing on a cell has its trace from the cell already.
+func storeFinished(p *Player) {
+	if p.Position.X == -1 {
+		printerChan <- []board.Trace{grid.NewTrace(p.ID, p.Position, p.Symbol)}
+	}
+}
+
+var (
+	cells       [][]*Cell
+	grid        *board.Board
+	startSignal chan struct{}
+)
+
+// place puts traveler p on its start, or on a random free cell when it has
+// none or the start is taken. After as many random tries as the board has
+// cells it takes the first free cell instead, and reports false only when
+// there is none.
+func place(p *Player, starts scenario.Positions, setup *replay.Dice) bool {
+	occupy := func(pos board.Position) bool {
+		x, y := pos.X, pos.Y
+		if !cells[x][y].Lock(mainTask) {
+			return false
+		}
+		defer cells[x][y].Unlock(mainTask)
+		if cells[x][y].IsOccupied(mainTask) {
+			return false
+		}
+		cells[x][y].Occupy(mainTask, p)
+		p.Position = pos
+		cells[x][y].storeTrace(mainTask, p) // Record initial placement
+		return true
+	}
+	if pos, ok := starts.Start(grid, p.ID, p.Symbol); ok && occupy(pos) {
+		return true
+	}
+	for range grid.Width * grid.Height {
+		if occupy(setup.Position(grid)) {
+			return true
+		}
+		grid.Time().Sleep(1 * time.Millisecond) // Avoid tight loop
+	}
+	for y := range grid.Height {
+		for x := range grid.Width {
+			pos := board.Position{X: x, Y: y}
+			if !grid.Wall(pos) && occupy(pos) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkStart reports why the snapshot s cannot start a run on grid.
+func checkStart(s *snapshot.State) error {
+	if err := s.Fits(grid); err != nil {
+		return err
+	}
+	if len(s.Cells) == 0 {
+		return fmt.Errorf("snapshot has no cells to start from")
+	}
+	for _, c := range s.Cells {
+		pos := board.Position{X: c.X, Y: c.Y}
+		if !grid.Contains(pos) {
+			return fmt.Errorf("snapshot cell (%d,%d) is off the board", c.X, c.Y)
+		}
+		if grid.Wall(pos) && (c.Occupied || c.Trapped) {
+			return fmt.Errorf("snapshot cell (%d,%d) is a wall", c.X, c.Y)
+		}
+		if c.Occupied && (c.Occupant < 0 || c.Occupant >= len(players) || c.Wild != players[c.Occupant].Wild) {
+			return fmt.Errorf("snapshot cell (%d,%d) holds unknown occupant %d", c.X, c.Y, c.Occupant)
+		}
+	}
+	for _, p := range players {
+		task, ok := s.Task(p.ID)
+		if !ok {
+			return fmt.Errorf("snapshot has no task %d", p.ID)
+		}
+		if len([]rune(task.Symbol)) != 1 {
+			return fmt.Errorf("snapshot task %d has symbol %q", p.ID, task.Symbol)
+		}
+	}
+	return nil
+}
+
+// Main runs the simulation with the parameters given on the command line.
+func Main() {
+	flag.IntVar(&NrOfTravelers, "travelers", NrOfTravelers, "number of travelers")
+	flag.IntVar(&NrOfWildTenants, "wild-tenants", NrOfWildTenants, "number of wild tenants")
+	scenario.Steps(&MinSteps, &MaxSteps)
+	scenario.Delays(&MinDelay, &MaxDelay)
+	flag.DurationVar(&WildTenantLifetime, "wild-tenant-lifetime", WildTenantLifetime, "time a wild tenant stays on the board")
+	flag.IntVar(&BoardWidth, "width", BoardWidth, "board width, unless -map sets it")
+	flag.IntVar(&BoardHeight, "height", BoardHeight, "board height, unless -map sets it")
+	flag.IntVar(&TrapsCount, "traps", TrapsCount, "number of traps")
+	flag.IntVar(&ShardSize, "shard", ShardSize, "cells per side of the region one actor runs, 1 for an actor per cell")
+	flag.BoolVar(&DEBUG, "debug", DEBUG, "print every cell message to stdout")
+	output := scenario.RegisterOutput()
+	options := replay.RegisterFlags()
+	boardOptions := board.RegisterFlags()
+	starts := scenario.RegisterStarts()
+	var trapsAt scenario.Positions
+	flag.Var(&trapsAt, "trap-at", "space separated x,y cells of the first traps; the rest lie on random cells")
+	strategies := flag.String("strategy", "random", "space separated movement strategies, given to travelers in turn")
+	check := flag.Bool("check", false, "stop at the first occupancy violation")
+	snapshotPath := flag.String("snapshot", "", "write a snapshot of the running board to `file`")
+	snapshotAfter := flag.Duration("snapshot-after", 250*time.Millisecond, "how long into the run the snapshot is taken")
+	startPath := flag.String("start", "", "start from the snapshot in `file` instead of placing everything anew")
+	flag.Parse()
+	scenario.Check(
+		scenario.AtLeast("travelers", NrOfTravelers, 1),
+		scenario.AtLeast("wild tenants", NrOfWildTenants, 0),
+		scenario.Range("steps", MinSteps, MaxSteps),
+		scenario.Range("delays", MinDelay, MaxDelay),
+		scenario.AtLeast("width", BoardWidth, 1),
+		scenario.AtLeast("height", BoardHeight, 1),
+		scenario.AtLeast("shard", ShardSize, 1),
+		scenario.AtLeast("traps", TrapsCount, len(trapsAt)),
+	)
+	var err error
+	if out, err = output.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// The snapshot to start from, if any, goes on with its own seed
+	var start *snapshot.State
+	if *startPath != "" {
+		if start, err = snapshot.Load(*startPath); err == nil {
+			err = options.UseSeed(start.Seed)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	decisions, err := options.Open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	setup := decisions.Dice(replay.SetupID)
+
+	grid, err = boardOptions.New(BoardWidth, BoardHeight)
+	if err == nil && grid.Depth != 1 {
+		err = fmt.Errorf("cells are kept per (x, y), %s boards are not supported", grid.Topology)
+	} else if err == nil && grid.Shared() {
+		err = fmt.Errorf("cells hold one occupant, capacities above 1 are not supported")
+	} else if err == nil {
+		if err = starts.On(grid); err == nil {
+			err = trapsAt.On(grid)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	scenario.Check(scenario.AtMost("travelers", NrOfTravelers, grid.Room()))
+	occupancy := checker.New()
+	if *check {
+		grid.AddHook(occupancy.Hook(checker.FailFast))
+	}
+	printerChan = make(chan []board.Trace, 1000)
+	startSignal = make(chan struct{})
+	wg = sync.WaitGroup{}
+
+	players = make([]*Player, NrOfTravelers+NrOfWildTenants)
+	for i := 0; i < NrOfTravelers; i++ {
+		players[i] = &Player{
+			ID:       i,
+			Symbol:   rune('A' + i),
+			Wild:     false,
+			Position: board.Position{X: -1, Y: -1}, // Temporary invalid position
+		}
+	}
+
+	// Create wild tenants (0-9)
+	for i := 0; i < NrOfWildTenants; i++ {
+		players[NrOfTravelers+i] = &Player{
+			ID:     NrOfTravelers + i,
+			Symbol: rune('0' + i%10),
+			Wild:   true,
+			pushed: make(chan push, 1),
+		}
+	}
+
+	if start != nil {
+		if err := checkStart(start); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		start.Resume(grid)
+	}
+
+	// Cell actors stop on interrupt or once the run is over. With
+	// -snapshot an interrupt takes the snapshot first.
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	running, halt := context.WithCancel(context.Background())
+	defer halt()
+	system := actor.Start(running)
+
+	// Initialize board
+	data := make([][]cellData, grid.Width)
+	for i := range data {
+		data[i] = make([]cellData, grid.Height)
+		for j := range data[i] {
+			data[i][j].wall = grid.Wall(board.Position{X: i, Y: j})
+		}
+	}
+	if start != nil {
+		for _, c := range start.Cells {
+			d := &data[c.X][c.Y]
+			d.locked, d.occupied, d.wild, d.occupant = c.Locked, c.Occupied, c.Wild, c.Occupant
+			if c.Trapped {
+				d.trapped = true
+				d.trap = Trap{Position: board.Position{X: c.X, Y: c.Y}, ID: c.TrapID}
+			}
+		}
+	}
+	regions, err := shard.New(system, grid.Width, grid.Height, ShardSize, func(at board.Position, e envelope) {
+		cells[at.X][at.Y].handle(e)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	cells = make([][]*Cell, grid.Width)
+	for i := range cells {
+		cells[i] = make([]*Cell, grid.Height)
+		for j := range cells[i] {
+			cells[i][j] = NewCell(regions, board.Position{X: i, Y: j}, data[i][j])
+		}
+	}
+
+	// The traps on the board, to count them in the header
+	traps := 0
+	if start == nil {
+		// Placing traps in different positions
+		var allPositions []board.Position
+		for x := 0; x < grid.Width; x++ {
+			for y := 0; y < grid.Height; y++ {
+				if pos := (board.Position{X: x, Y: y}); !grid.Wall(pos) {
+					allPositions = append(allPositions, pos)
+				}
+			}
+		}
+		setup.Shuffle(len(allPositions), func(i, j int) {
+			allPositions[i], allPositions[j] = allPositions[j], allPositions[i]
+		})
+		// The cells of -trap-at come first
+		positions := slices.Clone(trapsAt)
+		for _, pos := range allPositions {
+			if !slices.Contains(trapsAt, pos) {
+				positions = append(positions, pos)
+			}
+		}
+		for i := 0; i < TrapsCount && i < len(positions); i++ {
+			pos := positions[i]
+			cells[pos.X][pos.Y].AddTrap(mainTask, NrOfTravelers+NrOfWildTenants+i, pos.X, pos.Y)
+			cells[pos.X][pos.Y].storeTrap(mainTask)
+			traps++
+			if DEBUG {
+				fmt.Printf("Placed trap at (%d,%d)\n", pos.X, pos.Y)
+			}
+		}
+	} else {
+		// Players stand where the cells say, traps stay where they were
+		for _, p := range players {
+			task, _ := start.Task(p.ID)
+			p.Symbol = []rune(task.Symbol)[0]
+			p.Position = board.Position{X: -1, Y: -1}
+		}
+		for _, c := range start.Cells {
+			if c.Trapped {
+				cells[c.X][c.Y].storeTrap(mainTask)
+				traps++
+			}
+			if c.Occupied {
+				occupant := players[c.Occupant]
+				occupant.Position = board.Position{X: c.X, Y: c.Y}
+				cells[c.X][c.Y].storeTrace(mainTask, occupant)
+			}
+		}
+	}
+
+	// Print parameters
+	grid.PrintParameters(out, NrOfTravelers+NrOfWildTenants+traps)
+
+	// Start printer as a separate goroutine; tasks and cells hand in their
+	// traces in no particular order, so they are printed in stamp order
+	// once all are in
+	printerDone := make(chan struct{})
+	go func() {
+		var all []board.Trace
+		for traces := range printerChan {
+			all = append(all, traces...)
+		}
+		board.SortTraces(all)
+		grid.PrintTraces(out, all)
+		close(printerDone)
+	}()
+
+	// Place travelers on their -starts, their map starts or randomly
+	for i := 0; start == nil && i < NrOfTravelers; i++ {
+		if !place(players[i], *starts, setup) {
+			fmt.Fprintf(os.Stderr, "no room for traveler %c\n", players[i].Symbol)
+			os.Exit(1)
+		}
+	}
+
+	// Initialize traveler tasks
+	specs := strategy.Specs(*strategies)
+	for i := 0; i < NrOfTravelers; i++ {
+		var dice *replay.Dice
+		var moves strategy.Strategy
+		var nrOfSteps int
+		spec := strategy.Pick(specs, i)
+		if start != nil {
+			task, _ := start.Task(i)
+			if task.Done || players[i].Position.X == -1 {
+				leaveSnapshots(i, task)
+				storeFinished(players[i])
+				continue
+			}
+			// The dice and strategy of the snapshot, not the flags
+			spec, nrOfSteps = task.Strategy, task.Steps
+			dice, moves, err = task.Moves(grid, decisions)
+		} else {
+			dice = decisions.Dice(i)
+			nrOfSteps = MinSteps + dice.Intn(MaxSteps-MinSteps+1)
+			moves, err = strategy.New(spec, grid, dice)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		wg.Add(1)
+		activeTravelers.Increment()
+		marker := joinSnapshots(i)
+		grid.Time().Go(func() { travelerTask(i, nrOfSteps, dice, spec, moves, marker) })
+	}
+
+	// Initialize wild tenant tasks
+	for i := 0; i < NrOfWildTenants; i++ {
+		id := NrOfTravelers + i
+		var life time.Duration
+		dice := decisions.Dice(id)
+		if start != nil {
+			task, _ := start.Task(id)
+			if task.Done {
+				leaveSnapshots(id, task)
+				storeFinished(players[id])
+				continue
+			}
+			if players[id].Position.X != -1 {
+				life = max(task.Life, time.Nanosecond)
+			}
+			dice = decisions.Resume(id, task.Draws)
+		}
+		wg.Add(1)
+		activeWildTenants.Increment()
+		marker := joinSnapshots(id)
+		grid.Time().Go(func() { wildTenantTask(id, life, dice, marker) })
+	}
+
+	// The snapshot is due -snapshot-after into the run on the board clock
+	due := make(chan struct{})
+	if *snapshotPath != "" {
+		grid.Time().AfterFunc(*snapshotAfter, func() { close(due) })
+	}
+
+	// Start all tasks
+	close(startSignal)
+	grid.Time().Start()
+
+	// Take a snapshot while they run, on interrupt or of the end of a
+	// shorter run. An interrupt then stops the cells.
+	finished := make(chan struct{})
+	snapshotDone := make(chan error, 1)
+	go func() {
+		if *snapshotPath == "" {
+			snapshotDone <- nil
+		} else {
+			select {
+			case <-due:
+			case <-interrupted.Done():
+			case <-finished:
+			}
+			state, err := TakeSnapshot(decisions.Seed, system.Done())
+			if err == nil {
+				err = state.Save(*snapshotPath)
+			}
+			snapshotDone <- err
+		}
+		select {
+		case <-interrupted.Done():
+			halt()
+		case <-finished:
+		}
+	}()
+
+	// Wait for completion
+	wg.Wait()
+	close(finished)
+	if err := <-snapshotDone; err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *check {
+		for _, v := range occupancy.Flush() {
+			checker.FailFast(v)
+		}
+	}
+
+	// Collect all cell traces, one cell after another: on large boards a
+	// goroutine per cell would cost more than the run itself
+	for x := 0; x < grid.Width; x++ {
+		for y := 0; y < grid.Height; y++ {
+			if traces := cells[x][y].ExportTraces(mainTask); len(traces) > 0 {
+				printerChan <- traces
+			}
+		}
+	}
+
+	// Cleanup
+	close(printerChan) // Signal printer to exit
+	<-printerDone      // Wait for printer to finish
+
+	// Stop cell actors and make sure none is left behind
+	system.Stop()
+	if n := system.Running(); n != 0 {
+		fmt.Fprintf(os.Stderr, "%d cell goroutines still running\n", n)
+		os.Exit(1)
+	}
+
+	if err := options.Close(decisions); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
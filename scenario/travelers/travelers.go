@@ -0,0 +1,209 @@
+// Package travelers to lista1 zadanie1: podroznicy chodza po planszy bez
+// zadnej synchronizacji, wiec moga stanac na tym samym polu.
+package travelers
+
+import "flag"
+import "fmt"
+import "io"
+import "os"
+import "time"
+
+import "github.com/dawid831/ParallelProgramming/board"
+import "github.com/dawid831/ParallelProgramming/replay"
+import "github.com/dawid831/ParallelProgramming/scenario"
+import "github.com/dawid831/ParallelProgramming/snapshot"
+import "github.com/dawid831/ParallelProgramming/strategy"
+
+// parametry, domyslnie dawne stale; flagi Main je zmieniaja
+var (
+	NrOfTravelers = 15
+	MinSteps      = 10
+	MaxSteps      = 100
+	MinDelay      = 10 * time.Millisecond
+	MaxDelay      = 50 * time.Millisecond
+	BoardWidth    = 15
+	BoardHeight   = 15
+)
+
+// out dostaje slady, stdout albo plik z -out
+var out io.Writer = os.Stdout
+
+// Funkcja do gorutyny symulujacej podroznika; po zamknieciu pause
+// zatrzymuje sie miedzy krokami i oddaje swoj stan do checkpointu
+func run(t *board.Traveler, nrOfSteps int, dice *replay.Dice, spec string, moves strategy.Strategy, pause <-chan struct{}, done chan<- snapshot.Task) {
+	b := t.Board()
+	for i := 0; i < nrOfSteps; i++ {
+		select {
+		case <-pause:
+			b.PrintTraces(out, t.Traces)
+			done <- snapshot.Traveler(t, nrOfSteps-i, dice, spec, moves)
+			return
+		default:
+		}
+		b.Time().Sleep(dice.Delay(MinDelay, MaxDelay))
+		d, ok := moves.Next(strategy.NewView(b, t.Position, nil))
+		if !ok {
+			continue
+		}
+		if !t.Move(d) {
+			continue
+		}
+		t.StoreTrace()
+	}
+	b.PrintTraces(out, t.Traces)
+	done <- snapshot.Traveler(t, 0, dice, spec, moves)
+}
+
+// Main uruchamia symulacje z parametrami z linii polecen.
+func Main() {
+	flag.IntVar(&NrOfTravelers, "travelers", NrOfTravelers, "number of travelers")
+	scenario.Steps(&MinSteps, &MaxSteps)
+	scenario.Delays(&MinDelay, &MaxDelay)
+	flag.IntVar(&BoardWidth, "width", BoardWidth, "board width, unless -map sets it")
+	flag.IntVar(&BoardHeight, "height", BoardHeight, "board height, unless -map sets it")
+	output := scenario.RegisterOutput()
+	options := replay.RegisterFlags()
+	boardOptions := board.RegisterFlags()
+	starts := scenario.RegisterStarts()
+	strategies := flag.String("strategy", "random", "space separated movement strategies, given to travelers in turn")
+	checkpoint := flag.String("checkpoint", "", "on interrupt stop the travelers between steps and write their state to `file`")
+	checkpointAfter := flag.Duration("checkpoint-after", 0, "also stop for the checkpoint this long into the run (0: only on interrupt)")
+	resume := flag.String("resume", "", "resume the run from the checkpoint in `file`")
+	flag.Parse()
+	scenario.Check(
+		scenario.AtLeast("travelers", NrOfTravelers, 1),
+		scenario.Range("steps", MinSteps, MaxSteps),
+		scenario.Range("delays", MinDelay, MaxDelay),
+		scenario.AtLeast("width", BoardWidth, 1),
+		scenario.AtLeast("height", BoardHeight, 1),
+	)
+	var err error
+	if out, err = output.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// checkpoint do wznowienia, z ziarnem tamtego przebiegu
+	var start *snapshot.State
+	if *resume != "" {
+		if start, err = snapshot.Load(*resume); err == nil {
+			err = options.UseSeed(start.Seed)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	decisions, err := options.Open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	setup := decisions.Dice(replay.SetupID)
+
+	b, err := boardOptions.New(BoardWidth, BoardHeight)
+	if err == nil && start != nil {
+		err = start.Fits(b)
+	}
+	if err == nil {
+		err = starts.On(b)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if start != nil {
+		start.Resume(b)
+	}
+	b.PrintParameters(out, NrOfTravelers)
+	travelers := make([]*board.Traveler, NrOfTravelers)
+	symbol := 'A'
+	done := make(chan snapshot.Task, NrOfTravelers)
+
+	// Tworzenie podroznikow i ich danych
+	for i := 0; i < NrOfTravelers; i++ {
+		if start != nil {
+			// z checkpointu: to samo miejsce i symbol
+			task, ok := start.Task(i)
+			if !ok {
+				err = fmt.Errorf("checkpoint has no traveler %d", i)
+			} else {
+				travelers[i], err = task.Traveler(b)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			travelers[i].StoreTrace()
+			continue
+		}
+		// start z -starts, z mapy albo losowa pozycja
+		pos, ok := starts.Start(b, i, symbol)
+		if !ok {
+			pos = setup.Position(b)
+		}
+		travelers[i] = b.NewTraveler(i, symbol, pos)
+		travelers[i].StoreTrace()
+		symbol++
+	}
+
+	// Wybor strategii ruchu dla kazdego podroznika, a z checkpointu
+	// strategia, kosci i pozostale kroki tamtego przebiegu
+	specs := strategy.Specs(*strategies)
+	dice := make([]*replay.Dice, NrOfTravelers)
+	moves := make([]strategy.Strategy, NrOfTravelers)
+	picked := make([]string, NrOfTravelers)
+	steps := make([]int, NrOfTravelers)
+	for i := 0; i < NrOfTravelers; i++ {
+		if start != nil {
+			task, _ := start.Task(i)
+			if dice[i], moves[i], err = task.Moves(b, decisions); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			picked[i], steps[i] = task.Strategy, task.Steps
+			continue
+		}
+		dice[i] = decisions.Dice(i)
+		picked[i] = strategy.Pick(specs, i)
+		if moves[i], err = strategy.New(picked[i], b, dice[i]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		steps[i] = MinSteps + dice[i].Intn(MaxSteps-MinSteps+1)
+	}
+
+	// Z -checkpoint przerwanie (albo uplyw -checkpoint-after) zatrzymuje
+	// podroznikow miedzy krokami zamiast konczyc program
+	var pause <-chan struct{}
+	if *checkpoint != "" {
+		pause = snapshot.Pause(b.Time(), *checkpointAfter)
+	}
+
+	// Start podroznikow jako zadan zegara planszy; na zegarze wirtualnym
+	// ruszaja dopiero wszystkie razem po Start
+	for i := 0; i < NrOfTravelers; i++ {
+		b.Time().Go(func() {
+			run(travelers[i], steps[i], dice[i], picked[i], moves[i], pause, done)
+		})
+	}
+	b.Time().Start()
+
+	// Czeka na zakończenie wszystkich gorutyn
+	tasks := make([]snapshot.Task, 0, NrOfTravelers)
+	for i := 0; i < NrOfTravelers; i++ {
+		tasks = append(tasks, <-done)
+	}
+	if *checkpoint != "" {
+		state := snapshot.New(b, decisions.Seed)
+		state.Tasks = tasks
+		if err := state.Save(*checkpoint); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if err := options.Close(decisions); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,326 @@
+// Package travelerslocked to lista1 zadanie3: podroznik zajmuje pole, na
+// ktore wchodzi, i zwalnia to, z ktorego schodzi, wiec dwoch nigdy nie
+// stoi na jednym polu.
+package travelerslocked
+
+import "flag"
+import "fmt"
+import "io"
+import "os"
+import "strings"
+import "time"
+
+import "github.com/dawid831/ParallelProgramming/board"
+import "github.com/dawid831/ParallelProgramming/checker"
+import "github.com/dawid831/ParallelProgramming/replay"
+import "github.com/dawid831/ParallelProgramming/scenario"
+import "github.com/dawid831/ParallelProgramming/snapshot"
+import "github.com/dawid831/ParallelProgramming/strategy"
+
+// parametry, domyslnie dawne stale; flagi Main je zmieniaja
+var (
+	NrOfTravelers = 15
+	MinSteps      = 10
+	MaxSteps      = 100
+	MinDelay      = 10 * time.Millisecond
+	MaxDelay      = 50 * time.Millisecond
+	BoardWidth    = 15
+	BoardHeight   = 15
+)
+
+// out dostaje slady, stdout albo plik z -out
+var out io.Writer = os.Stdout
+
+// Zajete pole to pole bez wolnego miejsca
+func occupied(b *board.Board) func(board.Position) bool {
+	return func(p board.Position) bool {
+		return b.Cell(p).Full()
+	}
+}
+
+// Zajmuje pola ksztaltu podroznika i: start z -starts albo z mapy, potem
+// losowe pola, a po tylu probach ile pol na planszy pierwsze wolne po
+// kolei; false gdy nigdzie sie nie miesci
+func place(b *board.Board, shape board.Shape, starts scenario.Positions, i int, symbol rune, setup *replay.Dice) (board.Position, bool) {
+	take := func(pos board.Position) bool {
+		cells, fits := b.Cover(pos, shape)
+		return fits && b.LockAll(cells, 1*time.Millisecond)
+	}
+	if pos, ok := starts.Start(b, i, symbol); ok && take(pos) {
+		return pos, true
+	}
+	for range b.Width * b.Height * b.Depth {
+		if pos := setup.Position(b); take(pos) {
+			return pos, true
+		}
+	}
+	for z := 0; z < b.Depth; z++ {
+		for y := 0; y < b.Height; y++ {
+			for x := 0; x < b.Width; x++ {
+				if pos := (board.Position{X: x, Y: y, Z: z}); take(pos) {
+					return pos, true
+				}
+			}
+		}
+	}
+	return board.Position{}, false
+}
+
+// Funkcja do gorutyny symulujacej podroznika; po zamknieciu pause
+// zatrzymuje sie miedzy krokami i oddaje swoj stan do checkpointu
+func run(t *board.Traveler, nrOfSteps int, dice *replay.Dice, spec string, moves strategy.Strategy, swaps *board.Exchange, pause <-chan struct{}, done chan<- snapshot.Task) {
+	b := t.Board()
+	for i := 0; i < nrOfSteps; i++ {
+		select {
+		case <-pause:
+			t.Account()
+			b.PrintTraces(out, t.Traces)
+			done <- snapshot.Traveler(t, nrOfSteps-i, dice, spec, moves)
+			return
+		default:
+		}
+		b.Time().Sleep(dice.Delay(MinDelay, MaxDelay))
+
+		// próba kroku
+		d, ok := moves.Next(strategy.NewView(b, t.Position, occupied(b)))
+		if !ok {
+			continue
+		}
+		target, ok := b.Move(t.Position, d)
+		if !ok {
+			continue
+		}
+
+		// z -swap: ruch albo zamiana miejscami z sasiadem (tylko jedno pole)
+		if swaps != nil && len(t.Cells()) == 1 {
+			if swaps.Step(t, target, 2*MaxDelay) == board.TimedOut {
+				t.Freeze()
+				t.StoreTrace()
+				break
+			}
+			continue
+		}
+
+		// próba zajęcia nowych pol z timeoutem, trace przed zwolnieniem starych
+		if !t.StepTo(target, 2*MaxDelay) {
+			// porazka
+			t.Freeze()
+			t.StoreTrace()
+			break
+		}
+	}
+	t.Account()
+	b.PrintTraces(out, t.Traces)
+	done <- snapshot.Traveler(t, 0, dice, spec, moves)
+}
+
+// Main uruchamia symulacje z parametrami z linii polecen.
+func Main() {
+	flag.IntVar(&NrOfTravelers, "travelers", NrOfTravelers, "number of travelers")
+	scenario.Steps(&MinSteps, &MaxSteps)
+	scenario.Delays(&MinDelay, &MaxDelay)
+	flag.IntVar(&BoardWidth, "width", BoardWidth, "board width, unless -map sets it")
+	flag.IntVar(&BoardHeight, "height", BoardHeight, "board height, unless -map sets it")
+	output := scenario.RegisterOutput()
+	options := replay.RegisterFlags()
+	boardOptions := board.RegisterFlags()
+	starts := scenario.RegisterStarts()
+	check := flag.Bool("check", false, "stop at the first occupancy violation")
+	strategies := flag.String("strategy", "random", "space separated movement strategies, given to travelers in turn")
+	swap := flag.Bool("swap", false, "let neighbours that want each other's cells swap places")
+	hold := flag.Duration("hold", MinDelay, "time a move keeps both cells held per unit of terrain cost (road 1, grass 2, swamp 4), on maps with terrain")
+	shapes := flag.String("shape", "single", "space separated traveler shapes (single, domino, block, l), given to travelers in turn")
+	checkpoint := flag.String("checkpoint", "", "on interrupt stop the travelers between steps and write their state to `file`")
+	checkpointAfter := flag.Duration("checkpoint-after", 0, "also stop for the checkpoint this long into the run (0: only on interrupt)")
+	resume := flag.String("resume", "", "resume the run from the checkpoint in `file`")
+	flag.Parse()
+	scenario.Check(
+		scenario.AtLeast("travelers", NrOfTravelers, 1),
+		scenario.Range("steps", MinSteps, MaxSteps),
+		scenario.Range("delays", MinDelay, MaxDelay),
+		scenario.AtLeast("width", BoardWidth, 1),
+		scenario.AtLeast("height", BoardHeight, 1),
+	)
+	var err error
+	if out, err = output.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// checkpoint do wznowienia, z ziarnem tamtego przebiegu
+	var start *snapshot.State
+	if *resume != "" {
+		if start, err = snapshot.Load(*resume); err == nil {
+			err = options.UseSeed(start.Seed)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	decisions, err := options.Open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	setup := decisions.Dice(replay.SetupID)
+
+	b, err := boardOptions.New(BoardWidth, BoardHeight)
+	if err == nil && start != nil {
+		err = start.Fits(b)
+	}
+	if err == nil {
+		err = starts.On(b)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if start != nil {
+		start.Resume(b)
+	}
+	if b.Weighted() {
+		b.HoldUnit = *hold
+	}
+	occupancy := checker.New()
+	occupancy.Capacity = b.Capacity
+	if *check {
+		b.AddHook(occupancy.Hook(checker.FailFast))
+	}
+
+	// Ksztalty podroznikow, odrzucone gdy nigdy sie nie zmieszcza
+	var kinds []board.Shape
+	for _, name := range strings.Fields(*shapes) {
+		shape, err := board.ParseShape(name)
+		if err == nil {
+			err = b.Fits(shape)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		kinds = append(kinds, shape)
+	}
+	if len(kinds) == 0 {
+		kinds = []board.Shape{board.Single}
+	}
+	// wszystkie ksztalty naraz musza zmiescic sie na wolnych polach
+	need := 0
+	for i := 0; i < NrOfTravelers; i++ {
+		need += len(kinds[i%len(kinds)])
+	}
+	scenario.Check(scenario.AtMost("traveler cells", need, b.Room()))
+
+	b.PrintParameters(out, NrOfTravelers)
+	travelers := make([]*board.Traveler, NrOfTravelers)
+	symbol := 'A'
+	done := make(chan snapshot.Task, NrOfTravelers)
+
+	// Tworzenie podroznikow i ich danych
+	for i := 0; i < NrOfTravelers; i++ {
+		if start != nil {
+			// z checkpointu: te same pola, zajete od nowa
+			task, ok := start.Task(i)
+			if !ok {
+				err = fmt.Errorf("checkpoint has no traveler %d", i)
+			} else if travelers[i], err = task.Traveler(b); err == nil && !b.LockAll(travelers[i].Cells(), 1*time.Millisecond) {
+				err = fmt.Errorf("traveler %d: cells %v are taken", i, travelers[i].Cells())
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			travelers[i].StoreTrace()
+			continue
+		}
+		shape := kinds[i%len(kinds)]
+		// start z -starts, z mapy albo proba znalezienia wolnych pol
+		pos, ok := place(b, shape, *starts, i, symbol, setup)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "no room for traveler %c\n", symbol)
+			os.Exit(1)
+		}
+
+		travelers[i] = b.NewTraveler(i, symbol, pos)
+		travelers[i].Shape = shape
+		travelers[i].StoreTrace()
+		symbol++
+	}
+	// Wybor strategii ruchu dla kazdego podroznika, a z checkpointu
+	// strategia, kosci i pozostale kroki tamtego przebiegu
+	specs := strategy.Specs(*strategies)
+	dice := make([]*replay.Dice, NrOfTravelers)
+	moves := make([]strategy.Strategy, NrOfTravelers)
+	picked := make([]string, NrOfTravelers)
+	steps := make([]int, NrOfTravelers)
+	for i := 0; i < NrOfTravelers; i++ {
+		if start != nil {
+			task, _ := start.Task(i)
+			if dice[i], moves[i], err = task.Moves(b, decisions); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			picked[i], steps[i] = task.Strategy, task.Steps
+			continue
+		}
+		dice[i] = decisions.Dice(i)
+		picked[i] = strategy.Pick(specs, i)
+		if moves[i], err = strategy.New(picked[i], b, dice[i]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		steps[i] = MinSteps + dice[i].Intn(MaxSteps-MinSteps+1)
+	}
+
+	var swaps *board.Exchange
+	if *swap {
+		swaps = board.NewExchange(b)
+	}
+
+	// Z -checkpoint przerwanie (albo uplyw -checkpoint-after) zatrzymuje
+	// podroznikow miedzy krokami zamiast konczyc program
+	var pause <-chan struct{}
+	if *checkpoint != "" {
+		pause = snapshot.Pause(b.Time(), *checkpointAfter)
+	}
+
+	// Start podroznikow jako zadan zegara planszy; na zegarze wirtualnym
+	// ruszaja dopiero wszystkie razem po Start
+	for i := 0; i < NrOfTravelers; i++ {
+		b.Time().Go(func() {
+			run(travelers[i], steps[i], dice[i], picked[i], moves[i], swaps, pause, done)
+		})
+	}
+	b.Time().Start()
+
+	// Czeka na zakończenie wszystkich gorutyn
+	tasks := make([]snapshot.Task, 0, NrOfTravelers)
+	for i := 0; i < NrOfTravelers; i++ {
+		tasks = append(tasks, <-done)
+	}
+	if *checkpoint != "" {
+		state := snapshot.New(b, decisions.Seed)
+		state.Tasks = tasks
+		if err := state.Save(*checkpoint); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	// czas spedzony na kazdym terenie
+	if b.Weighted() {
+		var spent board.TerrainTimes
+		for _, t := range travelers {
+			spent.Add(t.Spent)
+		}
+		spent.Print(os.Stderr)
+	}
+	if *check {
+		for _, v := range occupancy.Flush() {
+			checker.FailFast(v)
+		}
+	}
+	if err := options.Close(decisions); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,736 @@
+// Package wildtenants is lista2 zadanie2: travelers and wild tenants on a
+// board whose cells are actors. A traveler stepping on a wild tenant pushes
+// it aside, and wild tenants leave the board when their lifetime is over.
+package wildtenants
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/dawid831/ParallelProgramming/actor"
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/replay"
+	"github.com/dawid831/ParallelProgramming/scenario"
+	"github.com/dawid831/ParallelProgramming/shard"
+)
+
+// Parameters, the former constants; Main sets them from its flags.
+var (
+	NrOfTravelers      = 15
+	NrOfWildTenants    = 10
+	MinSteps           = 10
+	MaxSteps           = 100
+	MinDelay           = 10 * time.Millisecond
+	MaxDelay           = 50 * time.Millisecond
+	WildTenantLifetime = 500 * time.Millisecond
+	BoardWidth         = 15
+	BoardHeight        = 15
+	ShardSize          = 1
+	DEBUG              = false
+)
+
+// out receives the traces, stdout or the file of -out.
+var out io.Writer = os.Stdout
+
+type Player struct {
+	ID       int
+	Symbol   rune
+	Position board.Position
+	Wild     bool
+	// pushed brings a wild tenant the latest place a traveler pushed it
+	// to. Only the tenant's own task writes its Position.
+	pushed chan board.Position
+}
+
+// push tells the task of wild tenant p where it stands now, replacing a
+// push it has not taken yet. A tenant is pushed by one traveler at a time,
+// the one holding the lock of the cell it stands on.
+func (p *Player) push(position board.Position) {
+	select {
+	case <-p.pushed:
+	default:
+	}
+	p.pushed <- position
+}
+
+type AtomicCounter struct {
+	count int
+	mu    sync.Mutex
+}
+
+func (c *AtomicCounter) Increment() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count++
+}
+
+func (c *AtomicCounter) Decrement() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.count--
+}
+
+func (c *AtomicCounter) GetCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.count
+}
+
+// Messages understood by a Cell. A request that expects an answer carries
+// its own reply channel. Handlers only touch the cell's own fields; whatever
+// they need about players arrives as a copy inside the message.
+type cellMessage interface {
+	cellMessage()
+}
+
+type lockRequest struct{ reply chan bool }
+
+type unlockRequest struct{}
+
+type occupyRequest struct {
+	id   int
+	wild bool
+}
+
+type clearRequest struct{}
+
+type queryRequest struct{ reply chan cellState }
+
+// evictRequest removes the wild tenant id, if it still lives in the cell.
+type evictRequest struct {
+	id    int
+	reply chan bool
+}
+
+// traceRequest stores trace, stamped by the sender.
+type traceRequest struct{ trace board.Trace }
+
+type exportRequest struct{ reply chan []board.Trace }
+
+func (lockRequest) cellMessage()   {}
+func (unlockRequest) cellMessage() {}
+func (occupyRequest) cellMessage() {}
+func (clearRequest) cellMessage()  {}
+func (queryRequest) cellMessage()  {}
+func (evictRequest) cellMessage()  {}
+func (traceRequest) cellMessage()  {}
+func (exportRequest) cellMessage() {}
+
+// cellState is what a query tells about a cell.
+type cellState struct {
+	locked   bool
+	occupied bool
+	wild     bool
+	occupant int
+}
+
+type Cell struct {
+	regions  *shard.Board[cellMessage]
+	position board.Position
+	locked   bool
+	occupied bool
+	wall     bool
+	wild     bool
+	occupant int
+	traces   []board.Trace
+}
+
+// NewCell makes the cell at position, run by its region of regions. Once
+// the system of the regions stops, every command to the cell fails with
+// actor.ErrStopped.
+func NewCell(regions *shard.Board[cellMessage], position board.Position, wall bool) *Cell {
+	return &Cell{regions: regions, position: position, wall: wall}
+}
+
+func (c *Cell) handle(msg cellMessage) {
+	if DEBUG {
+		fmt.Printf("Cell starts %T\n", msg)
+	}
+	switch m := msg.(type) {
+	case lockRequest:
+		ok := !c.locked && !c.wall
+		if ok {
+			c.locked = true
+		}
+		m.reply <- ok
+	case unlockRequest:
+		c.locked = false
+	case occupyRequest:
+		c.occupant = m.id
+		c.wild = m.wild
+		c.occupied = true
+	case clearRequest:
+		c.occupied = false
+	case queryRequest:
+		m.reply <- cellState{
+			locked:   c.locked,
+			occupied: c.occupied,
+			wild:     c.wild,
+			occupant: c.occupant,
+		}
+	case evictRequest:
+		ok := c.occupied && c.wild && c.occupant == m.id
+		if ok {
+			c.occupied = false
+		}
+		m.reply <- ok
+	case traceRequest:
+		c.traces = append(c.traces, m.trace)
+	case exportRequest:
+		traces := make([]board.Trace, len(c.traces))
+		copy(traces, c.traces)
+		m.reply <- traces
+	default:
+		panic(fmt.Sprintf("cell: unknown message %T", msg))
+	}
+	if DEBUG {
+		fmt.Printf("Cell ends\n")
+	}
+}
+
+func (c *Cell) send(msg cellMessage) error {
+	return c.regions.Send(c.position, msg)
+}
+
+func (c *Cell) query() (cellState, error) {
+	reply := make(chan cellState, 1)
+	if err := c.send(queryRequest{reply: reply}); err != nil {
+		return cellState{}, err
+	}
+	return <-reply, nil
+}
+
+// Lock asks for the cell; the attempt is a scheduling point of the clock.
+func (c *Cell) Lock() bool {
+	grid.Time().Yield()
+	if DEBUG {
+		fmt.Printf("Locking\n")
+	}
+	reply := make(chan bool, 1)
+	if c.send(lockRequest{reply: reply}) != nil {
+		return false
+	}
+	return <-reply
+}
+
+func (c *Cell) Unlock() error {
+	if DEBUG {
+		fmt.Printf("Unlocking\n")
+	}
+	return c.send(unlockRequest{})
+}
+
+func (c *Cell) IsLocked() bool {
+	if DEBUG {
+		fmt.Printf("IsLocked\n")
+	}
+	// A stopped cell can no longer be entered.
+	state, err := c.query()
+	return err != nil || state.locked
+}
+
+func (c *Cell) Occupy(mover *Player) error {
+	if DEBUG {
+		fmt.Printf("Occupy\n")
+	}
+	return c.send(occupyRequest{id: mover.ID, wild: mover.Wild})
+}
+
+func (c *Cell) IsOccupied() bool {
+	if DEBUG {
+		fmt.Printf("IsOccupied\n")
+	}
+	state, err := c.query()
+	return err != nil || state.occupied
+}
+
+func (c *Cell) GetOccupant() int {
+	if DEBUG {
+		fmt.Printf("GetOccupant\n")
+	}
+	state, err := c.query()
+	if err != nil || !state.occupied {
+		return -1
+	}
+	return state.occupant
+}
+
+func (c *Cell) Clear() error {
+	if DEBUG {
+		fmt.Printf("Clear\n")
+	}
+	return c.send(clearRequest{})
+}
+
+// Evict takes the wild tenant with the given id out of the cell.
+func (c *Cell) Evict(id int) bool {
+	if DEBUG {
+		fmt.Printf("Evict\n")
+	}
+	reply := make(chan bool, 1)
+	if c.send(evictRequest{id: id, reply: reply}) != nil {
+		return false
+	}
+	return <-reply
+}
+
+// storeTrace records the occupant, whose current state the caller passes
+// in. The trace is stamped here, in the caller's goroutine.
+func (c *Cell) storeTrace(occupant *Player) error {
+	if DEBUG {
+		fmt.Printf("storeTrace\n")
+	}
+	trace := grid.NewTrace(occupant.ID, occupant.Position, occupant.Symbol)
+	return c.send(traceRequest{trace: trace})
+}
+
+func (c *Cell) ExportTraces() []board.Trace {
+	if DEBUG {
+		fmt.Printf("ExportTraces\n")
+	}
+	reply := make(chan []board.Trace, 1)
+	if c.send(exportRequest{reply: reply}) != nil {
+		return nil
+	}
+	return <-reply
+}
+
+// MoveWildTenant pushes the wild tenant standing on pos, if there is one, to
+// a free neighbouring cell. The caller holds the lock of pos, so the tenant
+// cannot leave on its own meanwhile. It runs in the caller's goroutine and
+// talks to each cell only through messages. It reports whether pos is free
+// of wild tenants afterwards.
+func MoveWildTenant(pos board.Position, cells [][]*Cell, dice *replay.Dice) bool {
+	if DEBUG {
+		fmt.Printf("MoveWild\n")
+	}
+	cell := cells[pos.X][pos.Y]
+	state, err := cell.query()
+	if err != nil {
+		return false
+	}
+	if !state.occupied || !state.wild {
+		return true
+	}
+	player := players[state.occupant]
+	dirs := append([]board.Direction(nil), grid.Directions()...)
+	dice.Shuffle(len(dirs), func(i, j int) {
+		dirs[i], dirs[j] = dirs[j], dirs[i]
+	})
+
+	for _, dir := range dirs {
+		newPos, ok := grid.Move(pos, dir)
+		if !ok {
+			continue
+		}
+		next := cells[newPos.X][newPos.Y]
+
+		if next.Lock() {
+			if !next.IsOccupied() {
+				next.Occupy(player)
+				player.push(newPos)
+				cell.Evict(player.ID)
+				next.send(traceRequest{trace: grid.NewTrace(player.ID, newPos, player.Symbol)})
+				next.Unlock()
+				return true
+			}
+			next.Unlock()
+		}
+	}
+	return false
+}
+
+var (
+	players           []*Player
+	activeTravelers   AtomicCounter
+	activeWildTenants AtomicCounter
+	printerChan       chan []board.Trace
+	wg                sync.WaitGroup
+)
+
+func travelerTask(id int, dice *replay.Dice) {
+	defer wg.Done()
+	<-startSignal
+
+	traveler := id
+	nrOfSteps := MinSteps + dice.Intn(MaxSteps-MinSteps+1)
+	var traces []board.Trace
+
+	storeTrace := func() {
+		traces = append(traces, grid.NewTrace(players[traveler].ID, players[traveler].Position, players[traveler].Symbol))
+	}
+
+	makeStep := func() {
+		current := players[traveler].Position
+		target, ok := grid.Move(current, dice.Direction(grid.Directions()))
+		if !ok {
+			return
+		}
+		currentX, currentY := current.X, current.Y
+		newX, newY := target.X, target.Y
+
+		if cells[newX][newY].Lock() {
+			if DEBUG {
+				fmt.Printf("%d locked %d %d\n", id, newX, newY)
+			}
+			if MoveWildTenant(target, cells, dice) {
+				if DEBUG {
+					fmt.Printf("%d emptied %d %d\n", id, newX, newY)
+				}
+				cells[newX][newY].Occupy(players[traveler])
+				players[traveler].Position = target
+				storeTrace()
+				cells[currentX][currentY].Unlock()
+			} else {
+				if unicode.IsUpper(players[traveler].Symbol) {
+					players[traveler].Symbol = unicode.ToLower(players[traveler].Symbol)
+				}
+				storeTrace()
+				cells[newX][newY].Unlock()
+			}
+		} else {
+			if unicode.IsUpper(players[traveler].Symbol) {
+				players[traveler].Symbol = unicode.ToLower(players[traveler].Symbol)
+			}
+			storeTrace()
+		}
+	}
+
+	for step := 0; step < nrOfSteps; step++ {
+		grid.Time().Sleep(dice.Delay(MinDelay, MaxDelay))
+		makeStep()
+
+		if !unicode.IsUpper(players[traveler].Symbol) {
+			break
+		}
+	}
+
+	if unicode.IsUpper(players[traveler].Symbol) {
+		players[traveler].Symbol = unicode.ToLower(players[traveler].Symbol)
+		storeTrace()
+	}
+
+	activeTravelers.Decrement()
+	printerChan <- traces
+}
+
+func wildTenantTask(id int, dice *replay.Dice) {
+	defer wg.Done()
+	<-startSignal
+
+	wildTenant := id
+	var traces []board.Trace
+	alive := false
+	var birthTime time.Duration
+
+	storeTrace := func() {
+		traces = append(traces, grid.NewTrace(players[wildTenant].ID, players[wildTenant].Position, players[wildTenant].Symbol))
+	}
+
+	// moved takes the place a traveler pushed the tenant to, if any
+	moved := func() {
+		select {
+		case pos := <-players[wildTenant].pushed:
+			players[wildTenant].Position = pos
+		default:
+		}
+	}
+
+	safeAppear := func() {
+		for attempt := 0; attempt < 10; attempt++ {
+			pos := dice.Position(grid)
+			x, y := pos.X, pos.Y
+
+			if cells[x][y].Lock() {
+				if !cells[x][y].IsOccupied() {
+					players[wildTenant].Position.X = x
+					players[wildTenant].Position.Y = y
+					players[wildTenant].Symbol = rune('0' + byte(players[wildTenant].ID%10))
+					cells[x][y].Occupy(players[wildTenant])
+					alive = true
+					birthTime = grid.Elapsed()
+					storeTrace()
+					cells[x][y].Unlock()
+					return
+				}
+				cells[x][y].Unlock()
+			}
+			grid.Time().Sleep(WildTenantLifetime / 10)
+		}
+	}
+
+	safeDisappear := func() {
+		x := players[wildTenant].Position.X
+		y := players[wildTenant].Position.Y
+
+		if cells[x][y].Lock() {
+			if cells[x][y].GetOccupant() == wildTenant {
+				players[wildTenant].Position.X = -1
+				players[wildTenant].Position.Y = -1
+				cells[x][y].Clear()
+				storeTrace()
+				alive = false
+			}
+			cells[x][y].Unlock()
+		}
+	}
+
+	for {
+		moved()
+		if !alive {
+			if DEBUG {
+				fmt.Printf("APPEARING\n")
+			}
+			safeAppear()
+		} else {
+			if grid.Elapsed()-birthTime > WildTenantLifetime {
+				safeDisappear()
+			}
+		}
+
+		if activeTravelers.GetCount() < 1 {
+			break
+		}
+		grid.Time().Sleep(10 * time.Millisecond)
+	}
+
+	activeWildTenants.Decrement()
+	printerChan <- traces
+}
+
+// place puts traveler p on its start, or on a random free cell when it has
+// none or the start is taken. After as many random tries as the board has
+// cells it takes the first free cell instead, and reports false only when
+// there is none.
+func place(p *Player, starts scenario.Positions, setup *replay.Dice) bool {
+	occupy := func(pos board.Position) bool {
+		x, y := pos.X, pos.Y
+		if !cells[x][y].Lock() {
+			return false
+		}
+		defer cells[x][y].Unlock()
+		if cells[x][y].IsOccupied() {
+			return false
+		}
+		cells[x][y].Occupy(p)
+		p.Position = pos
+		cells[x][y].storeTrace(p) // Record initial placement
+		return true
+	}
+	if pos, ok := starts.Start(grid, p.ID, p.Symbol); ok && occupy(pos) {
+		return true
+	}
+	for range grid.Width * grid.Height {
+		if occupy(setup.Position(grid)) {
+			return true
+		}
+		grid.Time().Sleep(1 * time.Millisecond) // Avoid tight loop
+	}
+	for y := range grid.Height {
+		for x := range grid.Width {
+			pos := board.Position{X: x, Y: y}
+			if !grid.Wall(pos) && occupy(pos) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	cells       [][]*Cell
+	grid        *board.Board
+	startSignal chan struct{}
+)
+
+// Main runs the simulation with the parameters given on the command line.
+func Main() {
+	flag.IntVar(&NrOfTravelers, "travelers", NrOfTravelers, "number of travelers")
+	flag.IntVar(&NrOfWildTenants, "wild-tenants", NrOfWildTenants, "number of wild tenants")
+	scenario.Steps(&MinSteps, &MaxSteps)
+	scenario.Delays(&MinDelay, &MaxDelay)
+	flag.DurationVar(&WildTenantLifetime, "wild-tenant-lifetime", WildTenantLifetime, "time a wild tenant stays on the board")
+	flag.IntVar(&BoardWidth, "width", BoardWidth, "board width, unless -map sets it")
+	flag.IntVar(&BoardHeight, "height", BoardHeight, "board height, unless -map sets it")
+	flag.IntVar(&ShardSize, "shard", ShardSize, "cells per side of the region one actor runs, 1 for an actor per cell")
+	flag.BoolVar(&DEBUG, "debug", DEBUG, "print every cell message to stdout")
+	output := scenario.RegisterOutput()
+	options := replay.RegisterFlags()
+	boardOptions := board.RegisterFlags()
+	starts := scenario.RegisterStarts()
+	flag.Parse()
+	scenario.Check(
+		scenario.AtLeast("travelers", NrOfTravelers, 1),
+		scenario.AtLeast("wild tenants", NrOfWildTenants, 0),
+		scenario.Range("steps", MinSteps, MaxSteps),
+		scenario.Range("delays", MinDelay, MaxDelay),
+		scenario.AtLeast("width", BoardWidth, 1),
+		scenario.AtLeast("height", BoardHeight, 1),
+		scenario.AtLeast("shard", ShardSize, 1),
+	)
+	var err error
+	if out, err = output.Open(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	decisions, err := options.Open()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	grid, err = boardOptions.New(BoardWidth, BoardHeight)
+	if err == nil && grid.Depth != 1 {
+		err = fmt.Errorf("cells are kept per (x, y), %s boards are not supported", grid.Topology)
+	} else if err == nil && grid.Shared() {
+		err = fmt.Errorf("cells hold one occupant, capacities above 1 are not supported")
+	} else if err == nil {
+		err = starts.On(grid)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	scenario.Check(scenario.AtMost("travelers", NrOfTravelers, grid.Room()))
+
+	// Cell actors stop on interrupt or once the run is over
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	system, err := run(ctx, decisions, *starts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// Make sure no cell actor is left behind
+	if n := system.Running(); n != 0 {
+		fmt.Fprintf(os.Stderr, "%d cell goroutines still running\n", n)
+		os.Exit(1)
+	}
+
+	if err := options.Close(decisions); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run plays one simulation on grid, writing its traces to out, and returns
+// the system of its cell actors once they have been stopped.
+func run(ctx context.Context, decisions *replay.Log, starts scenario.Positions) (*actor.System, error) {
+	setup := decisions.Dice(replay.SetupID)
+	printerChan = make(chan []board.Trace, 1000)
+	startSignal = make(chan struct{})
+	wg = sync.WaitGroup{}
+
+	players = make([]*Player, NrOfTravelers+NrOfWildTenants)
+	for i := 0; i < NrOfTravelers; i++ {
+		players[i] = &Player{
+			ID:       i,
+			Symbol:   rune('A' + i),
+			Wild:     false,
+			Position: board.Position{X: -1, Y: -1}, // Temporary invalid position
+		}
+	}
+
+	// Create wild tenants (0-9)
+	for i := 0; i < NrOfWildTenants; i++ {
+		players[NrOfTravelers+i] = &Player{
+			ID:     NrOfTravelers + i,
+			Symbol: rune('0' + i%10),
+			Wild:   true,
+			pushed: make(chan board.Position, 1),
+		}
+	}
+
+	system := actor.Start(ctx)
+	defer system.Stop()
+
+	// Initialize board, its cells run by regions of ShardSize x ShardSize
+	regions, err := shard.New(system, grid.Width, grid.Height, ShardSize, func(at board.Position, msg cellMessage) {
+		cells[at.X][at.Y].handle(msg)
+	})
+	if err != nil {
+		return system, err
+	}
+	cells = make([][]*Cell, grid.Width)
+	for i := range cells {
+		cells[i] = make([]*Cell, grid.Height)
+		for j := range cells[i] {
+			position := board.Position{X: i, Y: j}
+			cells[i][j] = NewCell(regions, position, grid.Wall(position))
+		}
+	}
+
+	// Print parameters
+	grid.PrintParameters(out, NrOfTravelers+NrOfWildTenants)
+
+	// Start printer as a separate goroutine; tasks and cells hand in their
+	// traces in no particular order, so they are printed in stamp order
+	// once all are in
+	printerDone := make(chan struct{})
+	go func() {
+		var all []board.Trace
+		for traces := range printerChan {
+			all = append(all, traces...)
+		}
+		board.SortTraces(all)
+		grid.PrintTraces(out, all)
+		close(printerDone)
+	}()
+
+	// Place travelers on their -starts, their map starts or randomly
+	for i := 0; i < NrOfTravelers; i++ {
+		if !place(players[i], starts, setup) {
+			close(printerChan)
+			<-printerDone
+			return system, fmt.Errorf("no room for traveler %c", players[i].Symbol)
+		}
+	}
+
+	// Initialize traveler tasks
+	wg.Add(NrOfTravelers)
+	for i := 0; i < NrOfTravelers; i++ {
+		activeTravelers.Increment()
+		dice := decisions.Dice(i)
+		grid.Time().Go(func() { travelerTask(i, dice) })
+	}
+
+	// Initialize wild tenant tasks
+	wg.Add(NrOfWildTenants)
+	for i := 0; i < NrOfWildTenants; i++ {
+		activeWildTenants.Increment()
+		dice := decisions.Dice(NrOfTravelers + i)
+		grid.Time().Go(func() { wildTenantTask(NrOfTravelers+i, dice) })
+	}
+
+	// Start all tasks
+	close(startSignal)
+	grid.Time().Start()
+
+	// Wait for completion
+	wg.Wait()
+
+	// Collect all cell traces, one cell after another: on large boards a
+	// goroutine per cell would cost more than the run itself
+	for x := 0; x < grid.Width; x++ {
+		for y := 0; y < grid.Height; y++ {
+			if traces := cells[x][y].ExportTraces(); len(traces) > 0 {
+				printerChan <- traces
+			}
+		}
+	}
+
+	// Cleanup
+	close(printerChan) // Signal printer to exit
+	<-printerDone      // Wait for printer to finish
+
+	// Stop cell actors
+	system.Stop()
+	return system, nil
+}
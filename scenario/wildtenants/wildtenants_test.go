@@ -0,0 +1,47 @@
+package wildtenants
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/replay"
+	"github.com/dawid831/ParallelProgramming/simtime"
+)
+
+func TestNoCellGoroutineOutlivesTheRun(t *testing.T) {
+	for _, size := range []int{1, 2} {
+		t.Run(fmt.Sprintf("shard-%d", size), func(t *testing.T) {
+			before := runtime.NumGoroutine()
+
+			NrOfTravelers, NrOfWildTenants = 4, 3
+			MinSteps, MaxSteps = 5, 10
+			WildTenantLifetime = 50 * time.Millisecond
+			ShardSize = size
+			grid = board.New(5, 5)
+			grid.SetTime(simtime.NewVirtual())
+			out = io.Discard
+
+			system, err := run(context.Background(), replay.NewLog(1), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := system.Running(); n != 0 {
+				t.Errorf("%d cell actors still running", n)
+			}
+			// the tasks, the printer and the system itself are gone as well
+			deadline := time.Now().Add(time.Second)
+			for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if n := runtime.NumGoroutine(); n > before {
+				buf := make([]byte, 1<<16)
+				t.Errorf("%d goroutines left after the run, %d before:\n%s", n, before, buf[:runtime.Stack(buf, true)])
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+// Package shard runs the cell actors of large boards in regions: one
+// goroutine owns a rectangle of cells instead of one goroutine per cell, so
+// a 1000x1000 board needs a few thousand actors rather than a million. The
+// cells keep their own state and handlers; a region only delivers the
+// messages addressed to its cells, one at a time.
+package shard
+
+import (
+	"fmt"
+
+	"github.com/dawid831/ParallelProgramming/actor"
+	"github.com/dawid831/ParallelProgramming/board"
+)
+
+// Board splits the cells of a width x height board into regions of Size x
+// Size cells, the last row and column of regions taking what is left. A
+// Size of 1 is one actor per cell. Messages from one sender to one cell
+// arrive in the order they were sent, as they would with actors per cell.
+type Board[M any] struct {
+	Width, Height, Size int
+	cols                int
+	regions             []*actor.Mailbox[addressed[M]]
+}
+
+// addressed is a message for the cell at.
+type addressed[M any] struct {
+	at  board.Position
+	msg M
+}
+
+// New starts the region actors of a width x height board in system. Each
+// calls handle for the messages of its cells, so handle must only touch the
+// state of the cell at, and not block on other cells of the board.
+func New[M any](system *actor.System, width, height, size int, handle func(at board.Position, msg M)) (*Board[M], error) {
+	if width < 1 || height < 1 {
+		return nil, fmt.Errorf("board %dx%d: sizes must be positive", width, height)
+	}
+	if size < 1 {
+		return nil, fmt.Errorf("shard size %d: must be positive", size)
+	}
+	b := &Board[M]{
+		Width:  width,
+		Height: height,
+		Size:   size,
+		cols:   (width + size - 1) / size,
+	}
+	rows := (height + size - 1) / size
+	for range rows * b.cols {
+		b.regions = append(b.regions, actor.Spawn(system, func(a addressed[M]) {
+			handle(a.at, a.msg)
+		}))
+	}
+	return b, nil
+}
+
+// Regions is the number of region actors.
+func (b *Board[M]) Regions() int {
+	return len(b.regions)
+}
+
+// Send hands msg to the region of the cell at, blocking until the region
+// takes it. After the system stopped it returns actor.ErrStopped.
+func (b *Board[M]) Send(at board.Position, msg M) error {
+	return b.regions[(at.Y/b.Size)*b.cols+at.X/b.Size].Send(addressed[M]{at: at, msg: msg})
+}
@@ -0,0 +1,256 @@
+package shard
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/actor"
+	"github.com/dawid831/ParallelProgramming/board"
+)
+
+// free marks a cell nobody occupies.
+const free = -1
+
+type kind int
+
+const (
+	enter kind = iota
+	leave
+	query
+)
+
+// request is a message of the occupancy cells below.
+type request struct {
+	kind  kind
+	id    int
+	reply chan int
+}
+
+// grid is a board of occupancy cells run by region actors. A traveler
+// takes the cell it moves to and only then leaves its old one, as with the
+// cell locks of lista1, so a move across regions is handed off between two
+// actors.
+type grid struct {
+	topology *board.Board
+	cells    *Board[request]
+	system   *actor.System
+	// occupant is only touched by the region of the cell.
+	occupant []int
+}
+
+func newGrid(width, height, size int) (*grid, error) {
+	g := &grid{
+		topology: board.New(width, height),
+		system:   actor.Start(context.Background()),
+		occupant: make([]int, width*height),
+	}
+	for i := range g.occupant {
+		g.occupant[i] = free
+	}
+	var err error
+	g.cells, err = New(g.system, width, height, size, func(at board.Position, req request) {
+		occupant := &g.occupant[at.Y*width+at.X]
+		switch req.kind {
+		case enter:
+			ok := *occupant == free
+			if ok {
+				*occupant = req.id
+			}
+			req.reply <- boolInt(ok)
+		case leave:
+			if *occupant == req.id {
+				*occupant = free
+			}
+		case query:
+			req.reply <- *occupant
+		}
+	})
+	return g, err
+}
+
+func boolInt(ok bool) int {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+func (g *grid) ask(p board.Position, req request) int {
+	req.reply = make(chan int, 1)
+	if g.cells.Send(p, req) != nil {
+		return free
+	}
+	return <-req.reply
+}
+
+func (g *grid) place(id int, p board.Position) bool {
+	return g.ask(p, request{kind: enter, id: id}) == 1
+}
+
+func (g *grid) move(id int, from, to board.Position) bool {
+	if g.ask(to, request{kind: enter, id: id}) != 1 {
+		return false
+	}
+	g.cells.Send(from, request{kind: leave, id: id})
+	return true
+}
+
+func (g *grid) occupantOf(p board.Position) int {
+	return g.ask(p, request{kind: query})
+}
+
+// place puts travelers on random free cells.
+func place(g *grid, travelers int) []board.Position {
+	positions := make([]board.Position, travelers)
+	r := rand.New(rand.NewSource(1))
+	for id := range positions {
+		for {
+			p := board.Position{X: r.Intn(g.topology.Width), Y: r.Intn(g.topology.Height)}
+			if g.place(id, p) {
+				positions[id] = p
+				break
+			}
+		}
+	}
+	return positions
+}
+
+// walk moves the travelers at random until steps moves were tried in total
+// and updates positions to where each one ended up.
+func walk(g *grid, positions []board.Position, steps int64) {
+	var left atomic.Int64
+	left.Store(steps)
+	dirs := g.topology.Directions()
+	var wg sync.WaitGroup
+	for id := range positions {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(id)))
+			for left.Add(-1) >= 0 {
+				from := positions[id]
+				to, ok := g.topology.Move(from, dirs[r.Intn(len(dirs))])
+				if ok && g.move(id, from, to) {
+					positions[id] = to
+				}
+			}
+		}(id)
+	}
+	wg.Wait()
+}
+
+func TestOccupancyStaysConsistent(t *testing.T) {
+	for _, size := range []int{1, 3, 7} {
+		t.Run(fmt.Sprintf("shard-%d", size), func(t *testing.T) {
+			g, err := newGrid(20, 12, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer g.system.Stop()
+			positions := place(g, 60)
+			walk(g, positions, 20000)
+
+			seen := make(map[board.Position]int)
+			for id, p := range positions {
+				if other, dup := seen[p]; dup {
+					t.Fatalf("travelers %d and %d both at %v", other, id, p)
+				}
+				seen[p] = id
+				if got := g.occupantOf(p); got != id {
+					t.Errorf("cell %v held by %d, traveler %d stands there", p, got, id)
+				}
+			}
+			for y := 0; y < 12; y++ {
+				for x := 0; x < 20; x++ {
+					p := board.Position{X: x, Y: y}
+					if _, taken := seen[p]; !taken && g.occupantOf(p) != free {
+						t.Errorf("cell %v held by %d after it was left", p, g.occupantOf(p))
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRegionsCoverUnevenBoards(t *testing.T) {
+	system := actor.Start(context.Background())
+	var mu sync.Mutex
+	got := make(map[board.Position]int)
+	b, err := New(system, 10, 7, 4, func(at board.Position, n int) {
+		mu.Lock()
+		defer mu.Unlock()
+		got[at] += n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Regions() != 6 {
+		t.Fatalf("Regions = %d, want 3x2", b.Regions())
+	}
+	for y := 0; y < 7; y++ {
+		for x := 0; x < 10; x++ {
+			if err := b.Send(board.Position{X: x, Y: y}, 1); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	system.Stop()
+	if len(got) != 70 {
+		t.Errorf("%d cells got their message, want 70", len(got))
+	}
+	for p, n := range got {
+		if n != 1 {
+			t.Errorf("cell %v got %d messages", p, n)
+		}
+	}
+	if b.Send(board.Position{}, 1) != actor.ErrStopped {
+		t.Error("stopped board took a message")
+	}
+}
+
+// heapAndStacks is the memory in use by the heap and goroutine stacks.
+func heapAndStacks() uint64 {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.HeapInuse + m.StackInuse
+}
+
+// maxCellActors keeps the per-cell design to boards it can start here: it
+// needs a goroutine, so at least one stack, per cell.
+const maxCellActors = 256 * 256
+
+// BenchmarkMoves compares one actor per cell (shard-1) with regions of
+// 16x16 and 64x64 cells. The board itself is the same for all of them, so
+// the difference in B/cell is what the actors cost.
+func BenchmarkMoves(b *testing.B) {
+	for _, size := range []int{64, 256, 1000} {
+		for _, shard := range []int{1, 16, 64} {
+			b.Run(fmt.Sprintf("shard-%d/%dx%d", shard, size, size), func(b *testing.B) {
+				if shard == 1 && size*size > maxCellActors {
+					b.Skipf("one goroutine per cell, %d cells", size*size)
+				}
+				before := heapAndStacks()
+				g, err := newGrid(size, size, shard)
+				if err != nil {
+					b.Fatal(err)
+				}
+				defer g.system.Stop()
+				memory := float64(heapAndStacks()) - float64(before)
+
+				positions := place(g, size*size/100)
+				b.ResetTimer()
+				start := time.Now()
+				walk(g, positions, int64(b.N))
+				b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "moves/s")
+				b.ReportMetric(memory/float64(size*size), "B/cell")
+			})
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package simtime
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Chooser picks the task that runs next on a controlled clock. ready are
+// the IDs of the tasks that can, at least two, in the order they were
+// started; last is the task whose turn just ended, -1 if none did.
+type Chooser interface {
+	Choose(last int, ready []int) int
+}
+
+// ParseChooser returns the chooser of a spec:
+//
+//	random            any task that can run, all alike
+//	pct[:D[:K]]       probabilistic concurrency testing: tasks run by random
+//	                  priority, and the running one drops to the bottom at
+//	                  D-1 random points among the first K choices
+//	                  (defaults 3 and 1000)
+//
+// seeded with seed.
+func ParseChooser(spec string, seed int64) (Chooser, error) {
+	name, args, _ := strings.Cut(spec, ":")
+	r := rand.New(rand.NewSource(seed))
+	switch name {
+	case "random":
+		if args != "" {
+			return nil, fmt.Errorf("chooser %q takes no arguments", spec)
+		}
+		return &randomChooser{r: r}, nil
+	case "pct":
+		depth, steps := 3, 1000
+		if args != "" {
+			d, k, hasK := strings.Cut(args, ":")
+			var err error
+			if depth, err = strconv.Atoi(d); err != nil || depth < 1 {
+				return nil, fmt.Errorf("chooser %q: bad depth %q", spec, d)
+			}
+			if hasK {
+				if steps, err = strconv.Atoi(k); err != nil || steps < 1 {
+					return nil, fmt.Errorf("chooser %q: bad steps %q", spec, k)
+				}
+			}
+		}
+		return newPCT(r, depth, steps), nil
+	}
+	return nil, fmt.Errorf("unknown chooser %q, want random or pct[:D[:K]]", spec)
+}
+
+type randomChooser struct {
+	r *rand.Rand
+}
+
+func (c *randomChooser) Choose(last int, ready []int) int {
+	return ready[c.r.Intn(len(ready))]
+}
+
+// pctChooser is the scheduler of Burckhardt et al., "A randomized scheduler
+// with probabilistic guarantees of finding bugs": a bug that needs D
+// orderings to show up is hit with probability at least 1/(n K^(D-1)) per
+// run of n tasks taking K steps.
+type pctChooser struct {
+	r        *rand.Rand
+	depth    int
+	priority map[int]int
+	// changes are the steps at which the running task drops, the i-th to
+	// priority i, below every task that never dropped.
+	changes []int
+	step    int
+}
+
+func newPCT(r *rand.Rand, depth, steps int) *pctChooser {
+	c := &pctChooser{r: r, depth: depth, priority: make(map[int]int)}
+	for i := 1; i < depth; i++ {
+		c.changes = append(c.changes, 1+r.Intn(steps))
+	}
+	return c
+}
+
+func (c *pctChooser) Choose(last int, ready []int) int {
+	c.step++
+	for _, id := range ready {
+		if _, known := c.priority[id]; !known {
+			c.priority[id] = c.depth + c.r.Intn(1<<30)
+		}
+	}
+	if last >= 0 {
+		for i, step := range c.changes {
+			if step == c.step {
+				c.priority[last] = i + 1
+			}
+		}
+	}
+	best := ready[0]
+	for _, id := range ready[1:] {
+		if c.priority[id] > c.priority[best] {
+			best = id
+		}
+	}
+	return best
+}
+
+// Replay returns a chooser that makes choices again. Past their end, or
+// once the run no longer offers the task they name, it keeps the last
+// task running as long as it can and says so on stderr.
+func Replay(choices []int) Chooser {
+	return &replayChooser{choices: choices}
+}
+
+type replayChooser struct {
+	choices  []int
+	at       int
+	diverged bool
+}
+
+func (c *replayChooser) Choose(last int, ready []int) int {
+	if !c.diverged {
+		if c.at < len(c.choices) && slices.Contains(ready, c.choices[c.at]) {
+			c.at++
+			return c.choices[c.at-1]
+		}
+		c.diverged = true
+		fmt.Fprintf(os.Stderr, "schedule diverges at choice %d of %d\n", c.at+1, len(c.choices))
+	}
+	return stay(last, ready)
+}
+
+// stay picks last if it can go on, the first task that can otherwise: the
+// choice that switches tasks the least.
+func stay(last int, ready []int) int {
+	if slices.Contains(ready, last) {
+		return last
+	}
+	return ready[0]
+}
+
+// WriteSchedule writes choices a task ID per line, after note as a comment
+// unless it is empty.
+func WriteSchedule(w io.Writer, note string, choices []int) error {
+	bw := bufio.NewWriter(w)
+	for _, line := range strings.Split(note, "\n") {
+		if line != "" {
+			fmt.Fprintf(bw, "# %s\n", line)
+		}
+	}
+	for _, id := range choices {
+		fmt.Fprintln(bw, id)
+	}
+	return bw.Flush()
+}
+
+// ReadSchedule reads choices written by WriteSchedule or recorded by a
+// controlled clock.
+func ReadSchedule(r io.Reader) ([]int, error) {
+	var choices []int
+	scanner := bufio.NewScanner(r)
+	lineNr := 0
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, err := strconv.Atoi(line)
+		if err != nil || id < 0 {
+			return nil, fmt.Errorf("line %d: bad task ID %q", lineNr, line)
+		}
+		choices = append(choices, id)
+	}
+	return choices, scanner.Err()
+}
@@ -0,0 +1,296 @@
+package simtime
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Controlled is a clock whose tasks take turns like those of Virtual, but a
+// Chooser, not the time of their wakeups, picks who runs next. A turn ends
+// at every Yield as well, so each lock attempt and each load or store of
+// shared state is a point where another task may cut in; and a sleeper may
+// be picked before a task due earlier, which then wakes late. Runs explore
+// interleavings that real timing rarely brings about, and repeat exactly
+// from the list of choices.
+//
+// The same rules as for Virtual apply: tasks block only through the clock,
+// and only tasks use it once it has started.
+type Controlled struct {
+	mu      sync.Mutex
+	chooser Chooser
+	now     time.Duration
+	tasks   []*task
+	running *task
+	started bool
+	// quiet is set while the running task has done nothing yet but find
+	// that what it waits for is not there.
+	quiet  bool
+	timers []timer
+	// choices are the IDs picked wherever more than one task could run,
+	// also written to record as they are made.
+	choices []int
+	record  io.Writer
+	stuck   func()
+}
+
+type taskState int
+
+const (
+	runnable taskState = iota
+	sleeping
+	waiting
+	finished
+)
+
+// task is one task of a controlled clock, identified by the order it was
+// started in.
+type task struct {
+	id    int
+	state taskState
+	// at is the wakeup of a sleeper, the deadline of a waiter with a
+	// timeout.
+	at    time.Duration
+	timed bool
+	// dirty is set for a waiter when another task did more than look since
+	// it last looked.
+	dirty bool
+	wake  chan struct{}
+}
+
+type timer struct {
+	at   time.Duration
+	fire func()
+}
+
+// NewControlled returns a controlled clock at time 0 that leaves the
+// choices to chooser and writes each one, a task ID per line, to record
+// unless it is nil. Its tasks run once Start is called.
+func NewControlled(chooser Chooser, record io.Writer) *Controlled {
+	return &Controlled{chooser: chooser, record: record}
+}
+
+// OnStuck makes the clock call f, instead of panicking, when every task
+// waits for another. The tasks then stay blocked for good.
+func (c *Controlled) OnStuck(f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stuck = f
+}
+
+// Choices returns the choices made so far.
+func (c *Controlled) Choices() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int(nil), c.choices...)
+}
+
+func (c *Controlled) Now() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Controlled) Set(now time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Sleep ends the turn of the calling task, which may be picked again at
+// any time; the clock then moves on to its wakeup if that is still ahead.
+func (c *Controlled) Sleep(d time.Duration) {
+	c.mu.Lock()
+	t := c.running
+	if !c.started || t == nil {
+		c.mu.Unlock()
+		return
+	}
+	t.state = sleeping
+	t.at = c.now + max(d, 0)
+	c.pass(t)
+	c.mu.Unlock()
+	<-t.wake
+}
+
+// Yield ends the turn of the calling task, which stays ready to run.
+func (c *Controlled) Yield() {
+	c.mu.Lock()
+	t := c.running
+	if !c.started || t == nil {
+		c.mu.Unlock()
+		return
+	}
+	t.state = runnable
+	c.pass(t)
+	c.mu.Unlock()
+	<-t.wake
+}
+
+// Wait gives the turn away whenever ready reports false. The task can be
+// picked to look again once another task did more than look; one with a
+// timeout can be picked at any time, and gives up when it still finds
+// nothing.
+func (c *Controlled) Wait(timeout time.Duration, ready func() bool) bool {
+	c.mu.Lock()
+	t := c.running
+	deadline := c.now + timeout
+	for {
+		c.mu.Unlock()
+		ok := ready()
+		c.mu.Lock()
+		if ok || !c.started || t == nil || timeout >= 0 && c.now >= deadline {
+			c.quiet = false
+			c.mu.Unlock()
+			return ok
+		}
+		t.state = waiting
+		t.at = deadline
+		t.timed = timeout >= 0
+		t.dirty = false
+		c.pass(t)
+		c.mu.Unlock()
+		<-t.wake
+		c.mu.Lock()
+	}
+}
+
+// AfterFunc calls f between two turns, once the clock has passed d from
+// now.
+func (c *Controlled) AfterFunc(d time.Duration, f func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.timers = append(c.timers, timer{at: c.now + max(d, 0), fire: f})
+}
+
+// Go adds task, ready to run.
+func (c *Controlled) Go(f func()) {
+	c.mu.Lock()
+	t := &task{id: len(c.tasks), wake: make(chan struct{}, 1)}
+	c.tasks = append(c.tasks, t)
+	c.mu.Unlock()
+	go func() {
+		<-t.wake
+		defer c.end(t)
+		f()
+	}()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next(nil)
+}
+
+func (c *Controlled) Start() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.started = true
+	c.next(nil)
+}
+
+// end ends the last turn of a task.
+func (c *Controlled) end(t *task) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t.state = finished
+	c.pass(t)
+}
+
+// pass ends the turn of t. Unless t only looked at what it waits for, the
+// waiting tasks may look again.
+func (c *Controlled) pass(t *task) {
+	if !c.quiet {
+		for _, o := range c.tasks {
+			if o != t && o.state == waiting {
+				o.dirty = true
+			}
+		}
+	}
+	c.running = nil
+	c.next(t)
+}
+
+// enabled reports whether picking t could let it go on.
+func (t *task) enabled() bool {
+	switch t.state {
+	case runnable, sleeping:
+		return true
+	case waiting:
+		return t.dirty || t.timed
+	}
+	return false
+}
+
+// next gives the turn to the task the chooser picks from those that can
+// run. last is the task whose turn just ended, nil if none did.
+func (c *Controlled) next(last *task) {
+	if !c.started || c.running != nil {
+		return
+	}
+	var ready []int
+	blocked := false
+	for _, t := range c.tasks {
+		if t.enabled() {
+			ready = append(ready, t.id)
+		} else if t.state == waiting {
+			blocked = true
+		}
+	}
+	if len(ready) == 0 {
+		if !blocked {
+			return
+		}
+		if c.stuck == nil {
+			panic("simtime: every task waits for another")
+		}
+		c.stuck()
+		return
+	}
+	id := ready[0]
+	if len(ready) > 1 {
+		lastID := -1
+		if last != nil {
+			lastID = last.id
+		}
+		id = c.chooser.Choose(lastID, ready)
+		c.choices = append(c.choices, id)
+		if c.record != nil {
+			fmt.Fprintln(c.record, id)
+		}
+	}
+	t := c.tasks[id]
+	c.quiet = false
+	switch t.state {
+	case sleeping:
+		c.now = max(c.now, t.at)
+	case waiting:
+		if t.dirty {
+			c.quiet = true
+		} else {
+			// Picked to give up.
+			c.now = max(c.now, t.at)
+		}
+	}
+	c.fire()
+	t.state = runnable
+	c.running = t
+	t.wake <- struct{}{}
+}
+
+// fire calls the functions of AfterFunc that are due, in the order they
+// fall due.
+func (c *Controlled) fire() {
+	for {
+		first := -1
+		for i, tm := range c.timers {
+			if tm.at <= c.now && (first < 0 || tm.at < c.timers[first].at) {
+				first = i
+			}
+		}
+		if first < 0 {
+			return
+		}
+		f := c.timers[first].fire
+		c.timers = append(c.timers[:first], c.timers[first+1:]...)
+		f()
+	}
+}
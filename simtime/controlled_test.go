@@ -0,0 +1,88 @@
+package simtime
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// peterson simulates two processes entering a critical section rounds
+// times by Peterson's algorithm. Raising the flag after giving the turn
+// away, as broken does, lets both in; the run then fails.
+func peterson(rounds int, broken bool) func(Clock) error {
+	return func(c Clock) error {
+		var flags [2]int
+		var turn, inside, overlaps int
+		var wg sync.WaitGroup
+		for i := range 2 {
+			other := 1 - i
+			wg.Add(1)
+			c.Go(func() {
+				defer wg.Done()
+				for range rounds {
+					c.Yield()
+					if broken {
+						turn = other
+					} else {
+						flags[i] = 1
+					}
+					c.Yield()
+					if broken {
+						flags[i] = 1
+					} else {
+						turn = other
+					}
+					c.Wait(-1, func() bool { return flags[other] == 0 || turn == i })
+					if inside++; inside > 1 {
+						overlaps++
+					}
+					c.Yield()
+					inside--
+					c.Yield()
+					flags[i] = 0
+				}
+			})
+		}
+		c.Start()
+		wg.Wait()
+		if overlaps > 0 {
+			return fmt.Errorf("both inside %d times", overlaps)
+		}
+		return nil
+	}
+}
+
+func TestExploreFindsAndReplaysBrokenPeterson(t *testing.T) {
+	for _, spec := range []string{"dfs:12", "random", "pct:3:40"} {
+		t.Run(spec, func(t *testing.T) {
+			failure, _, err := Explore(spec, 1, 500, peterson(3, true))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if failure == nil {
+				t.Fatal("no run let both processes in")
+			}
+			c := NewControlled(Replay(failure.Choices), nil)
+			if err := peterson(3, true)(c); err == nil {
+				t.Errorf("replay of run %d passed, it failed with %v", failure.Run, failure.Err)
+			}
+			if !slices.Equal(c.Choices(), failure.Choices) {
+				t.Errorf("replay chose %v, run %d chose %v", c.Choices(), failure.Run, failure.Choices)
+			}
+		})
+	}
+}
+
+func TestExhaustiveSearchPassesPeterson(t *testing.T) {
+	failure, runs, err := Explore("dfs:12", 0, 1<<13, peterson(3, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failure != nil {
+		t.Fatalf("run %d: %v", failure.Run, failure.Err)
+	}
+	if runs == 1<<13 {
+		t.Errorf("search of 12 binary choices did not end within %d runs", runs)
+	}
+}
@@ -0,0 +1,130 @@
+package simtime
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Failure is a run of Explore that failed, with the choices that led there.
+type Failure struct {
+	Run     int
+	Choices []int
+	Err     error
+}
+
+// Explore runs simulate on a new controlled clock, again and again, until
+// a run fails or runs runs have passed, and returns the first failure, nil
+// if none failed, and the number of runs made.
+//
+// simulate starts its tasks on the clock, starts it, waits for the tasks
+// and reports whether what it checks held. Given the same choices it must
+// do the same. A run in which every task waits for another fails; its
+// tasks are left blocked.
+//
+// spec is one of ParseChooser, each run seeded anew from seed, or
+//
+//	dfs[:D]   every way of making the first D choices (default 20) in turn,
+//	          the tasks switching as little as they can after that; the
+//	          search ends early once they are all tried
+func Explore(spec string, seed int64, runs int, simulate func(Clock) error) (*Failure, int, error) {
+	var dfs *dfsChooser
+	if name, args, _ := strings.Cut(spec, ":"); name == "dfs" {
+		dfs = &dfsChooser{depth: 20}
+		if args != "" {
+			depth, err := strconv.Atoi(args)
+			if err != nil || depth < 0 {
+				return nil, 0, fmt.Errorf("explorer %q: bad depth %q", spec, args)
+			}
+			dfs.depth = depth
+		}
+	} else if _, err := ParseChooser(spec, seed); err != nil {
+		return nil, 0, err
+	}
+	for run := 1; run <= runs; run++ {
+		var chooser Chooser = dfs
+		if dfs == nil {
+			chooser, _ = ParseChooser(spec, seed+int64(run-1))
+		}
+		c := NewControlled(chooser, nil)
+		stuck := make(chan struct{})
+		var once sync.Once
+		c.OnStuck(func() { once.Do(func() { close(stuck) }) })
+		done := make(chan error, 1)
+		go func() { done <- simulate(c) }()
+		var err error
+		select {
+		case err = <-done:
+		case <-stuck:
+			err = errors.New("every task waits for another")
+		}
+		if dfs != nil && dfs.diverged {
+			return nil, run, fmt.Errorf("run %d took another way after the same choices: the simulation is not deterministic", run)
+		}
+		if err != nil {
+			return &Failure{Run: run, Choices: c.Choices(), Err: err}, run, nil
+		}
+		if dfs != nil && !dfs.advance() {
+			return nil, run, nil
+		}
+	}
+	return nil, runs, nil
+}
+
+// dfsChooser searches the choices depth first, one run per path: it makes
+// the choices of the previous run again up to the last one that has an
+// alternative left, and takes that.
+type dfsChooser struct {
+	depth    int
+	branches []branch
+	at       int
+	diverged bool
+}
+
+// branch is one choice on the path: the tasks that could run, the one that
+// switches least first, and which of them is taken.
+type branch struct {
+	ready []int
+	pick  int
+}
+
+func (c *dfsChooser) Choose(last int, ready []int) int {
+	i := c.at
+	c.at++
+	if c.diverged || i >= c.depth {
+		return stay(last, ready)
+	}
+	ordered := make([]int, 0, len(ready))
+	if slices.Contains(ready, last) {
+		ordered = append(ordered, last)
+	}
+	for _, id := range ready {
+		if id != last {
+			ordered = append(ordered, id)
+		}
+	}
+	if i == len(c.branches) {
+		c.branches = append(c.branches, branch{ready: ordered})
+	} else if !slices.Equal(c.branches[i].ready, ordered) {
+		c.diverged = true
+		return stay(last, ready)
+	}
+	b := c.branches[i]
+	return b.ready[b.pick]
+}
+
+// advance moves on to the next path and reports whether there is one.
+func (c *dfsChooser) advance() bool {
+	c.at = 0
+	for len(c.branches) > 0 {
+		b := &c.branches[len(c.branches)-1]
+		if b.pick++; b.pick < len(b.ready) {
+			return true
+		}
+		c.branches = c.branches[:len(c.branches)-1]
+	}
+	return false
+}
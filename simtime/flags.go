@@ -0,0 +1,81 @@
+package simtime
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Options are the -virtual and -schedule command line flags.
+type Options struct {
+	Virtual      bool
+	Schedule     string
+	ScheduleSeed int64
+	RecordPath   string
+	ReplayPath   string
+}
+
+// RegisterFlags adds the clock flags to the default flag set.
+func RegisterFlags() *Options {
+	o := &Options{}
+	flag.BoolVar(&o.Virtual, "virtual", false, "run in virtual time: tasks take turns and every delay passes at once, so the run takes no longer than its computation")
+	flag.StringVar(&o.Schedule, "schedule", "", "let a chooser interleave the tasks at every lock attempt and shared load or store: random or pct[:depth[:steps]]")
+	flag.Int64Var(&o.ScheduleSeed, "schedule-seed", 0, "seed of the -schedule chooser (0 picks one from the clock)")
+	flag.StringVar(&o.RecordPath, "schedule-record", "", "write the choices of the scheduler to this file, one task ID per line")
+	flag.StringVar(&o.ReplayPath, "schedule-replay", "", "make the choices of the scheduler from this file again")
+	return o
+}
+
+// New returns the clock chosen on the command line. The seed of a chooser
+// is written to stderr so the run can be repeated with -schedule-seed, or
+// from the choices it records, which are written as they are made and so
+// survive a run that ends in a failed check.
+func (o *Options) New() (Clock, error) {
+	var chooser Chooser
+	switch {
+	case o.Virtual && (o.Schedule != "" || o.ReplayPath != ""):
+		return nil, errors.New("-virtual and -schedule pick different clocks")
+	case o.Schedule != "" && o.ReplayPath != "":
+		return nil, errors.New("-schedule and -schedule-replay pick different choosers")
+	case o.ReplayPath != "":
+		f, err := os.Open(o.ReplayPath)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		choices, err := ReadSchedule(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", o.ReplayPath, err)
+		}
+		chooser = Replay(choices)
+	case o.Schedule != "":
+		seed := o.ScheduleSeed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		var err error
+		if chooser, err = ParseChooser(o.Schedule, seed); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "schedule seed %d\n", seed)
+	case o.RecordPath != "":
+		return nil, errors.New("-schedule-record needs -schedule or -schedule-replay")
+	case o.Virtual:
+		return NewVirtual(), nil
+	default:
+		return NewWall(), nil
+	}
+	var record io.Writer
+	if o.RecordPath != "" {
+		// Left open for the rest of the run.
+		f, err := os.Create(o.RecordPath)
+		if err != nil {
+			return nil, err
+		}
+		record = f
+	}
+	return NewControlled(chooser, record), nil
+}
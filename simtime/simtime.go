@@ -0,0 +1,148 @@
+// Package simtime is the time the simulations run on: the wall clock, for
+// demos that play out at the pace of their delays, a virtual clock whose
+// discrete-event scheduler jumps straight to the next wakeup, for fast runs
+// that come out the same however loaded the machine is, or a controlled
+// clock that lets a chooser interleave the tasks, to search for the
+// orderings that break them.
+package simtime
+
+import (
+	"runtime"
+	"time"
+)
+
+// Clock is what the tasks of a simulation use instead of the time package.
+type Clock interface {
+	// Now is the time since the clock started.
+	Now() time.Duration
+	// Set moves the clock to now, so a run resumed from a snapshot goes
+	// on from the time it was taken. Call it before Start.
+	Set(now time.Duration)
+	// Sleep pauses the calling task for d.
+	Sleep(d time.Duration)
+	// Yield marks a point where another task may run first: a lock
+	// attempt, a load or store of shared state. Only a controlled clock
+	// switches tasks there.
+	Yield()
+	// Wait lets the other tasks run until ready reports true, for at most
+	// timeout or, when timeout is negative, for as long as it takes. It
+	// reports whether ready did. ready may be called many times and must
+	// change nothing unless it reports true.
+	Wait(timeout time.Duration, ready func() bool) bool
+	// AfterFunc calls f once d has passed. On a clock taking turns f runs
+	// between two turns, so every task sees what it did at the same point;
+	// it must not use the clock.
+	AfterFunc(d time.Duration, f func())
+	// Go starts task.
+	Go(task func())
+	// Start lets the tasks run. Tasks of a wall clock run as soon as they
+	// are started, those of the other clocks not before Start.
+	Start()
+}
+
+// Wall is real time; its tasks are plain goroutines.
+type Wall struct {
+	start time.Time
+}
+
+// NewWall starts a wall clock now.
+func NewWall() *Wall {
+	return &Wall{start: time.Now()}
+}
+
+func (w *Wall) Now() time.Duration {
+	return time.Since(w.start)
+}
+
+func (w *Wall) Set(now time.Duration) {
+	w.start = time.Now().Add(-now)
+}
+
+func (w *Wall) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (w *Wall) Yield() {}
+
+// Wait spins, giving up the processor between calls of ready, as the busy
+// waiting of the mutual exclusion algorithms always did.
+func (w *Wall) Wait(timeout time.Duration, ready func() bool) bool {
+	begin := time.Now()
+	for !ready() {
+		if timeout >= 0 && time.Since(begin) >= timeout {
+			return false
+		}
+		runtime.Gosched()
+	}
+	return true
+}
+
+func (w *Wall) AfterFunc(d time.Duration, f func()) {
+	time.AfterFunc(d, f)
+}
+
+func (w *Wall) Go(task func()) {
+	go task()
+}
+
+func (w *Wall) Start() {}
+
+// Turns reports whether the tasks of c take turns, so they must not block
+// but on c: whoever would unblock them only runs while they wait.
+func Turns(c Clock) bool {
+	_, wall := c.(*Wall)
+	return !wall
+}
+
+// Send puts v on ch within timeout, or without limit when timeout is
+// negative, and reports whether it did. On the wall clock it blocks on the
+// channel; a task of a clock that takes turns waits for them instead. The
+// attempt is a Yield point.
+func Send[T any](c Clock, ch chan<- T, v T, timeout time.Duration) bool {
+	c.Yield()
+	if Turns(c) {
+		return c.Wait(timeout, func() bool {
+			select {
+			case ch <- v:
+				return true
+			default:
+				return false
+			}
+		})
+	}
+	if timeout < 0 {
+		ch <- v
+		return true
+	}
+	select {
+	case ch <- v:
+		return true
+	default:
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case ch <- v:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// Receive takes a value from ch, the way Send puts one.
+func Receive[T any](c Clock, ch <-chan T) T {
+	c.Yield()
+	if !Turns(c) {
+		return <-ch
+	}
+	var v T
+	c.Wait(-1, func() bool {
+		select {
+		case v = <-ch:
+			return true
+		default:
+			return false
+		}
+	})
+	return v
+}
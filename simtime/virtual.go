@@ -0,0 +1,214 @@
+package simtime
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Virtual is a discrete-event clock. One of its tasks runs at a time and
+// computing takes no time at all: when the running task sleeps, waits or
+// ends, the task with the earliest wakeup runs next, ties in the order they
+// were scheduled, and the clock jumps to that wakeup.
+//
+// A task may only block through the clock, with Sleep, Wait, Send and
+// Receive. One blocked on a channel or mutex that another task would free
+// holds up the whole simulation, as that task never gets its turn.
+// Goroutines that are not tasks, like actors answering messages, run
+// freely, and only tasks may Sleep or Wait once the clock has started.
+type Virtual struct {
+	mu    sync.Mutex
+	now   time.Duration
+	queue []*event
+	// parked are the tasks in Wait, in the order they began waiting. Any
+	// turn that may have changed what they wait for lets them look again.
+	parked  []*event
+	started bool
+	running bool
+	// quiet is set while the running task has done nothing yet but find
+	// that what it waits for is not there.
+	quiet bool
+}
+
+// event is the wakeup of a task, or the call of a function of AfterFunc
+// when task is false.
+type event struct {
+	at   time.Duration
+	wake chan struct{}
+	fire func()
+	task bool
+	// waiting is set for a task in Wait, parked while it is in parked.
+	waiting, parked bool
+}
+
+// NewVirtual returns a virtual clock at time 0. Its tasks run once Start
+// is called.
+func NewVirtual() *Virtual {
+	return &Virtual{}
+}
+
+func (v *Virtual) Now() time.Duration {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.now
+}
+
+func (v *Virtual) Set(now time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.now = now
+}
+
+// Sleep ends the turn of the calling task until d has passed. Before Start,
+// while main sets the simulation up, no time passes and Sleep returns at
+// once.
+func (v *Virtual) Sleep(d time.Duration) {
+	v.mu.Lock()
+	if !v.started {
+		v.mu.Unlock()
+		return
+	}
+	e := &event{at: v.now + max(d, 0), wake: make(chan struct{}), task: true}
+	v.schedule(e)
+	v.pass()
+	v.dispatch()
+	v.mu.Unlock()
+	<-e.wake
+}
+
+// Yield does nothing: a task keeps its turn until it sleeps, waits or ends.
+func (v *Virtual) Yield() {}
+
+// Wait gives the turn away whenever ready reports false and looks again
+// after the next turn of another task that did more than look, or at the
+// timeout. Before Start it only calls ready once.
+func (v *Virtual) Wait(timeout time.Duration, ready func() bool) bool {
+	v.mu.Lock()
+	deadline := v.now + timeout
+	for {
+		v.mu.Unlock()
+		ok := ready()
+		v.mu.Lock()
+		if ok || !v.started || timeout >= 0 && v.now >= deadline {
+			v.quiet = false
+			v.mu.Unlock()
+			return ok
+		}
+		e := &event{wake: make(chan struct{}), task: true, waiting: true}
+		v.pass()
+		e.parked = true
+		v.parked = append(v.parked, e)
+		if timeout >= 0 {
+			e.at = deadline
+			v.schedule(e)
+		}
+		v.dispatch()
+		v.mu.Unlock()
+		<-e.wake
+		v.mu.Lock()
+	}
+}
+
+func (v *Virtual) AfterFunc(d time.Duration, f func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.schedule(&event{at: v.now + max(d, 0), fire: f})
+	v.dispatch()
+}
+
+// Go schedules task to run now, after the tasks already due.
+func (v *Virtual) Go(task func()) {
+	v.mu.Lock()
+	e := &event{at: v.now, wake: make(chan struct{}), task: true}
+	v.schedule(e)
+	v.dispatch()
+	v.mu.Unlock()
+	go func() {
+		<-e.wake
+		defer v.end()
+		task()
+	}()
+}
+
+func (v *Virtual) Start() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.started = true
+	v.dispatch()
+}
+
+// end ends the last turn of a task.
+func (v *Virtual) end() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.pass()
+	v.dispatch()
+}
+
+// schedule queues e after every event due no later than e.
+func (v *Virtual) schedule(e *event) {
+	i := sort.Search(len(v.queue), func(i int) bool {
+		return v.queue[i].at > e.at
+	})
+	v.queue = append(v.queue, nil)
+	copy(v.queue[i+1:], v.queue[i:])
+	v.queue[i] = e
+}
+
+// unschedule takes e out of the queue if it is there.
+func (v *Virtual) unschedule(e *event) {
+	for i, q := range v.queue {
+		if q == e {
+			v.queue = append(v.queue[:i], v.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// pass ends the turn of the running task. Unless the task only looked at
+// what it waits for, the parked tasks are due now, in the order they
+// parked, to look again.
+func (v *Virtual) pass() {
+	v.running = false
+	if v.quiet {
+		return
+	}
+	for _, e := range v.parked {
+		v.unschedule(e)
+		e.parked = false
+		e.at = v.now
+		v.schedule(e)
+	}
+	v.parked = nil
+}
+
+// dispatch gives the turn to the next task due, moving the clock to its
+// wakeup and calling the functions of AfterFunc on the way.
+func (v *Virtual) dispatch() {
+	for v.started && !v.running && len(v.queue) > 0 {
+		e := v.queue[0]
+		v.queue = v.queue[1:]
+		v.now = max(v.now, e.at)
+		if e.parked {
+			for i, p := range v.parked {
+				if p == e {
+					v.parked = append(v.parked[:i], v.parked[i+1:]...)
+					break
+				}
+			}
+			e.parked = false
+		}
+		if !e.task {
+			e.fire()
+			continue
+		}
+		v.running = true
+		v.quiet = e.waiting
+		close(e.wake)
+	}
+	if v.started && !v.running && len(v.parked) > 0 {
+		// Nothing is due that could change what the parked tasks wait
+		// for: each waits for another.
+		panic("simtime: every task waits for another")
+	}
+}
@@ -0,0 +1,96 @@
+package simtime
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSleepersWakeInTimeOrder(t *testing.T) {
+	v := NewVirtual()
+	var mu sync.Mutex
+	var woke []time.Duration
+	var wg sync.WaitGroup
+	for _, d := range []time.Duration{3 * time.Hour, time.Hour, 2 * time.Hour, time.Hour} {
+		wg.Add(1)
+		v.Go(func() {
+			defer wg.Done()
+			v.Sleep(d)
+			mu.Lock()
+			woke = append(woke, v.Now())
+			mu.Unlock()
+		})
+	}
+	begin := time.Now()
+	v.Start()
+	wg.Wait()
+	if real := time.Since(begin); real > time.Second {
+		t.Errorf("six hours of sleep took %v", real)
+	}
+	want := []time.Duration{time.Hour, time.Hour, 2 * time.Hour, 3 * time.Hour}
+	for i := range want {
+		if woke[i] != want[i] {
+			t.Fatalf("woke at %v, want %v", woke, want)
+		}
+	}
+}
+
+func TestWaitSeesTheTurnThatMadeItReady(t *testing.T) {
+	v := NewVirtual()
+	var flag bool
+	var seen, gaveUp time.Duration
+	var wg sync.WaitGroup
+	wg.Add(3)
+	v.Go(func() {
+		defer wg.Done()
+		v.Sleep(5 * time.Millisecond)
+		flag = true
+	})
+	v.Go(func() {
+		defer wg.Done()
+		if !v.Wait(-1, func() bool { return flag }) {
+			t.Error("Wait without timeout gave up")
+		}
+		seen = v.Now()
+	})
+	v.Go(func() {
+		defer wg.Done()
+		if v.Wait(2*time.Millisecond, func() bool { return flag }) {
+			t.Error("Wait saw the flag before it was set")
+		}
+		gaveUp = v.Now()
+	})
+	v.Start()
+	wg.Wait()
+	if seen != 5*time.Millisecond {
+		t.Errorf("flag seen at %v, set at 5ms", seen)
+	}
+	if gaveUp != 2*time.Millisecond {
+		t.Errorf("gave up at %v, timeout was 2ms", gaveUp)
+	}
+}
+
+func TestChannelHandoffBetweenTasks(t *testing.T) {
+	v := NewVirtual()
+	ch := make(chan int, 1)
+	got := make(chan int, 3)
+	v.Go(func() {
+		for i := 0; i < 3; i++ {
+			got <- Receive(v, ch)
+		}
+	})
+	v.Go(func() {
+		for i := 0; i < 3; i++ {
+			v.Sleep(time.Second)
+			if !Send(v, ch, i, time.Second) {
+				t.Error("no room in the channel")
+			}
+		}
+	})
+	v.Start()
+	for i := 0; i < 3; i++ {
+		if n := <-got; n != i {
+			t.Fatalf("received %d, want %d", n, i)
+		}
+	}
+}
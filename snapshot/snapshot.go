@@ -0,0 +1,190 @@
+// Package snapshot is the on-disk form of a board taken while it runs, as
+// written by the lista2 actor board and the lista1 traveler checkpoints and
+// read back as the start of a new run.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/simtime"
+)
+
+// Cell is the recorded state of one cell. In-transit messages have already
+// been applied to it.
+type Cell struct {
+	X, Y     int
+	Locked   bool `json:",omitempty"`
+	Occupied bool `json:",omitempty"`
+	Wild     bool `json:",omitempty"`
+	// Occupant is the ID of whoever is on an occupied cell. ID 0 is a
+	// valid occupant, so it is always written.
+	Occupant int
+	Trapped  bool `json:",omitempty"`
+	TrapID   int  `json:",omitempty"`
+}
+
+// Task is the recorded state of a traveler or wild tenant task. On the
+// actor board where it stands is kept by the cells, which are the only
+// consistent source.
+type Task struct {
+	ID     int
+	Symbol string
+	Wild   bool `json:",omitempty"`
+	// Steps is how many steps a traveler has left.
+	Steps int `json:",omitempty"`
+	// Life is how long a wild tenant on the board has left to live.
+	Life time.Duration `json:",omitempty"`
+	// Done marks a task that had already finished.
+	Done bool `json:",omitempty"`
+	// Draws is the state of the task's dice, see replay.Dice.Draws.
+	Draws uint64 `json:",omitempty"`
+	// Strategy is the spec the traveler moves by and StrategyState what
+	// a strategy.Stateful remembers.
+	Strategy      string `json:",omitempty"`
+	StrategyState int    `json:",omitempty"`
+	// Position and Shape place a lista1 traveler, whose cells are locks
+	// that record nothing themselves.
+	Position *board.Position `json:",omitempty"`
+	Shape    string          `json:",omitempty"`
+	// Spent is the time spent on each terrain so far.
+	Spent []time.Duration `json:",omitempty"`
+}
+
+// State is one consistent snapshot.
+type State struct {
+	Width, Height int
+	Depth         int `json:",omitempty"`
+	Topology      string
+	// Seed is the seed of the run, which the dice of every task are
+	// resumed from.
+	Seed int64 `json:",omitempty"`
+	// TakenAt is when the snapshot was started, from the board clock.
+	TakenAt time.Duration
+	// InTransit counts the messages recorded on channels between tasks
+	// and cells.
+	InTransit int `json:",omitempty"`
+	// Cells are empty for boards whose cells keep no state of their own.
+	Cells []Cell `json:",omitempty"`
+	Tasks []Task
+}
+
+// New starts the state of b at the current board time.
+func New(b *board.Board, seed int64) *State {
+	s := &State{
+		Width:    b.Width,
+		Height:   b.Height,
+		Topology: b.Topology.String(),
+		Seed:     seed,
+		TakenAt:  b.Elapsed(),
+	}
+	if b.Depth > 1 {
+		s.Depth = b.Depth
+	}
+	return s
+}
+
+// Fits reports why s cannot be resumed on b, nil when it can.
+func (s *State) Fits(b *board.Board) error {
+	depth := max(s.Depth, 1)
+	if s.Width != b.Width || s.Height != b.Height || depth != b.Depth || s.Topology != b.Topology.String() {
+		return fmt.Errorf("snapshot of a %dx%dx%d %s board, the board is %dx%dx%d %s",
+			s.Width, s.Height, depth, s.Topology, b.Width, b.Height, b.Depth, b.Topology)
+	}
+	return nil
+}
+
+// Resume sets the clock of b to where the snapshot was taken, so the
+// traces of the new run go on from there.
+func (s *State) Resume(b *board.Board) {
+	b.Time().Set(s.TakenAt)
+}
+
+// Write encodes s as indented JSON, tasks in ID order.
+func (s *State) Write(w io.Writer) error {
+	sort.Slice(s.Tasks, func(i, j int) bool {
+		return s.Tasks[i].ID < s.Tasks[j].ID
+	})
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// Save writes s to path.
+func (s *State) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := s.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read decodes a snapshot.
+func Read(r io.Reader) (*State, error) {
+	var s State
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+	if len(s.Cells) != 0 && len(s.Cells) != s.Width*s.Height {
+		return nil, fmt.Errorf("snapshot of %dx%d board has %d cells", s.Width, s.Height, len(s.Cells))
+	}
+	return &s, nil
+}
+
+// Load reads the snapshot at path.
+func Load(path string) (*State, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	s, err := Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Task returns the recorded state of task id.
+func (s *State) Task(id int) (Task, bool) {
+	for _, t := range s.Tasks {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Task{}, false
+}
+
+// Pause returns a channel closed on the first interrupt or, when after is
+// positive, once that much time has passed on clock c. The interrupt then
+// no longer ends the program, so its state can be saved.
+func Pause(c simtime.Clock, after time.Duration) <-chan struct{} {
+	pause := make(chan struct{})
+	var once sync.Once
+	stop := func() { once.Do(func() { close(pause) }) }
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	if after > 0 {
+		c.AfterFunc(after, stop)
+	}
+	go func() {
+		select {
+		case <-interrupted:
+		case <-pause:
+		}
+		signal.Stop(interrupted)
+		stop()
+	}()
+	return pause
+}
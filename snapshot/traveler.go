@@ -0,0 +1,72 @@
+package snapshot
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/replay"
+	"github.com/dawid831/ParallelProgramming/strategy"
+)
+
+// Traveler records a lista1 traveler t between two steps, with steps left
+// to go, its dice and the strategy moves built from spec.
+func Traveler(t *board.Traveler, steps int, dice *replay.Dice, spec string, moves strategy.Strategy) Task {
+	pos := t.Position
+	task := Task{
+		ID:       t.ID,
+		Symbol:   string(t.Symbol),
+		Steps:    steps,
+		Done:     steps == 0,
+		Draws:    dice.Draws(),
+		Strategy: spec,
+		Position: &pos,
+		Spent:    append([]time.Duration(nil), t.Spent[:]...),
+	}
+	if len(t.Shape) > 1 {
+		task.Shape = t.Shape.String()
+	}
+	if s, ok := moves.(strategy.Stateful); ok {
+		task.StrategyState = s.State()
+	}
+	return task
+}
+
+// Traveler puts the recorded traveler back on b. Its cells are left for
+// the caller to lock.
+func (t Task) Traveler(b *board.Board) (*board.Traveler, error) {
+	if t.Position == nil {
+		return nil, fmt.Errorf("task %d: no position recorded", t.ID)
+	}
+	symbol := []rune(t.Symbol)
+	if len(symbol) != 1 {
+		return nil, fmt.Errorf("task %d: symbol %q", t.ID, t.Symbol)
+	}
+	if !b.Contains(*t.Position) || b.Wall(*t.Position) {
+		return nil, fmt.Errorf("task %d: no room at %v", t.ID, *t.Position)
+	}
+	traveler := b.NewTraveler(t.ID, symbol[0], *t.Position)
+	if t.Shape != "" {
+		shape, err := board.ParseShape(t.Shape)
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %v", t.ID, err)
+		}
+		traveler.Shape = shape
+	}
+	copy(traveler.Spent[:], t.Spent)
+	return traveler, nil
+}
+
+// Moves resumes the dice of the task from log and rebuilds its strategy
+// on b.
+func (t Task) Moves(b *board.Board, log *replay.Log) (*replay.Dice, strategy.Strategy, error) {
+	dice := log.Resume(t.ID, t.Draws)
+	moves, err := strategy.New(t.Strategy, b, dice)
+	if err != nil {
+		return nil, nil, fmt.Errorf("task %d: %v", t.ID, err)
+	}
+	if s, ok := moves.(strategy.Stateful); ok {
+		s.SetState(t.StrategyState)
+	}
+	return dice, moves, nil
+}
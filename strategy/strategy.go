@@ -0,0 +1,292 @@
+// Package strategy decides where a traveler steps next. Each traveler gets
+// its own Strategy, so different kinds of travelers can share one board.
+package strategy
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/replay"
+)
+
+// View is the read-only part of the board a strategy may look at.
+type View struct {
+	Board    *board.Board
+	Position board.Position
+	occupied func(board.Position) bool
+}
+
+// NewView shows b around pos; occupied tells whether a cell is taken.
+func NewView(b *board.Board, pos board.Position, occupied func(board.Position) bool) View {
+	return View{Board: b, Position: pos, occupied: occupied}
+}
+
+// Occupied reports whether p is taken by someone.
+func (v View) Occupied(p board.Position) bool {
+	return v.occupied != nil && v.occupied(p)
+}
+
+// Free reports whether the step in direction d leads to a free cell.
+func (v View) Free(d board.Direction) bool {
+	next, ok := v.Board.Move(v.Position, d)
+	return ok && !v.Occupied(next)
+}
+
+// Strategy picks the next step. ok is false when the traveler should stay
+// where it is this turn.
+type Strategy interface {
+	Next(v View) (d board.Direction, ok bool)
+}
+
+// Stateful is a strategy that remembers something between steps beyond
+// its dice. A checkpoint keeps that state so a resumed traveler carries on
+// as before.
+type Stateful interface {
+	Strategy
+	State() int
+	SetState(state int)
+}
+
+// RandomWalk steps in a uniformly random direction.
+type RandomWalk struct {
+	Dice *replay.Dice
+}
+
+func (s *RandomWalk) Next(v View) (board.Direction, bool) {
+	return s.Dice.Direction(v.Board.Directions()), true
+}
+
+// Fixed always steps the same way.
+type Fixed struct {
+	Direction board.Direction
+}
+
+func (s *Fixed) Next(v View) (board.Direction, bool) {
+	return s.Direction, true
+}
+
+// Biased is a random walk that picks Towards Weight times more often than
+// any other direction.
+type Biased struct {
+	Dice    *replay.Dice
+	Towards board.Direction
+	Weight  int
+}
+
+func (s *Biased) Next(v View) (board.Direction, bool) {
+	dirs := v.Board.Directions()
+	roll := s.Dice.Intn(len(dirs) - 1 + s.Weight)
+	if roll < s.Weight {
+		return s.Towards, true
+	}
+	roll -= s.Weight
+	for _, d := range dirs {
+		if d == s.Towards {
+			continue
+		}
+		if roll == 0 {
+			return d, true
+		}
+		roll--
+	}
+	return s.Towards, true
+}
+
+// WallFollower keeps one hand on the wall: it turns towards that hand when
+// it can, goes straight otherwise, and turns further away the more it is
+// blocked. Edges and occupied cells count as wall. It stays in its layer.
+type WallFollower struct {
+	LeftHand bool
+	heading  int
+}
+
+// State is the index of the heading in the ring of the board.
+func (s *WallFollower) State() int {
+	return s.heading
+}
+
+func (s *WallFollower) SetState(state int) {
+	s.heading = state
+}
+
+func (s *WallFollower) Next(v View) (board.Direction, bool) {
+	ring := v.Board.Topology.Ring()
+	turn := 1
+	if s.LeftHand {
+		turn = -1
+	}
+	// Try hand side first, then sweep the other way round the ring.
+	for i := 0; i < len(ring); i++ {
+		h := ((s.heading+turn*(1-i))%len(ring) + len(ring)) % len(ring)
+		if v.Free(ring[h]) {
+			s.heading = h
+			return ring[h], true
+		}
+	}
+	return 0, false
+}
+
+// GoalSeeking walks a cheapest path around occupied cells to Goal and stays
+// there once it arrives. Entering a cell costs its terrain cost.
+type GoalSeeking struct {
+	Goal board.Position
+}
+
+func (s *GoalSeeking) Next(v View) (board.Direction, bool) {
+	if v.Position == s.Goal || !v.Board.Contains(s.Goal) {
+		return 0, false
+	}
+	return firstStep(v, s.Goal)
+}
+
+// firstStep runs Dijkstra from the goal back to the traveler, where a step
+// costs the terrain of the cell it enters. The neighbour with the cheapest
+// way on to the goal is the next step. On a board of plain road this is a
+// breadth-first search.
+func firstStep(v View, goal board.Position) (board.Direction, bool) {
+	dist := map[board.Position]int{goal: 0}
+	queue := &costQueue{{goal, 0}}
+	best, bestCost := board.Position{}, -1
+	for queue.Len() > 0 {
+		item := heap.Pop(queue).(costItem)
+		p := item.pos
+		if item.cost > dist[p] {
+			continue
+		}
+		if bestCost >= 0 && item.cost >= bestCost {
+			break
+		}
+		cost := item.cost + v.Board.Terrain(p).Cost()
+		for _, n := range v.Board.Neighbours(p) {
+			if n == v.Position {
+				if bestCost < 0 || cost < bestCost {
+					best, bestCost = p, cost
+				}
+				continue
+			}
+			if v.Occupied(n) {
+				continue
+			}
+			if d, seen := dist[n]; seen && d <= cost {
+				continue
+			}
+			dist[n] = cost
+			heap.Push(queue, costItem{n, cost})
+		}
+	}
+	if bestCost < 0 {
+		return 0, false
+	}
+	for _, d := range v.Board.Directions() {
+		if next, ok := v.Board.Move(v.Position, d); ok && next == best {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+type costItem struct {
+	pos  board.Position
+	cost int
+}
+
+// costQueue is a min-heap of cells by cost to the goal.
+type costQueue []costItem
+
+func (q costQueue) Len() int            { return len(q) }
+func (q costQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q costQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *costQueue) Push(x interface{}) { *q = append(*q, x.(costItem)) }
+func (q *costQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// Specs splits a space separated list of strategy specs.
+func Specs(list string) []string {
+	return strings.Fields(list)
+}
+
+// Pick returns the spec for traveler i, cycling through the list.
+func Pick(specs []string, i int) string {
+	if len(specs) == 0 {
+		return "random"
+	}
+	return specs[i%len(specs)]
+}
+
+// New builds a strategy from a spec:
+//
+//	random
+//	fixed:DIR
+//	biased:DIR[:WEIGHT]   (WEIGHT defaults to 3)
+//	wall:left | wall:right
+//	goal:X,Y[,Z]
+//
+// DIR is a direction name, e.g. UP or DOWN_LEFT, and has to be one of the
+// directions of board b.
+func New(spec string, b *board.Board, dice *replay.Dice) (Strategy, error) {
+	name, arg, _ := strings.Cut(spec, ":")
+	switch name {
+	case "random":
+		return &RandomWalk{Dice: dice}, nil
+	case "fixed":
+		d, err := direction(arg, b)
+		if err != nil {
+			return nil, fmt.Errorf("strategy %q: %v", spec, err)
+		}
+		return &Fixed{Direction: d}, nil
+	case "biased":
+		dir, weight, hasWeight := strings.Cut(arg, ":")
+		d, err := direction(dir, b)
+		if err != nil {
+			return nil, fmt.Errorf("strategy %q: %v", spec, err)
+		}
+		s := &Biased{Dice: dice, Towards: d, Weight: 3}
+		if hasWeight {
+			if s.Weight, err = strconv.Atoi(weight); err != nil || s.Weight < 1 {
+				return nil, fmt.Errorf("strategy %q: weight %q is not a positive integer", spec, weight)
+			}
+		}
+		return s, nil
+	case "wall":
+		if arg != "left" && arg != "right" {
+			return nil, fmt.Errorf("strategy %q: want wall:left or wall:right", spec)
+		}
+		return &WallFollower{LeftHand: arg == "left"}, nil
+	case "goal":
+		coords := strings.Split(arg, ",")
+		if len(coords) < 2 || len(coords) > 3 {
+			return nil, fmt.Errorf("strategy %q: want goal:X,Y[,Z]", spec)
+		}
+		var xyz [3]int
+		for i, c := range coords {
+			n, err := strconv.Atoi(c)
+			if err != nil {
+				return nil, fmt.Errorf("strategy %q: want goal:X,Y[,Z]", spec)
+			}
+			xyz[i] = n
+		}
+		return &GoalSeeking{Goal: board.Position{X: xyz[0], Y: xyz[1], Z: xyz[2]}}, nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q", spec)
+}
+
+// direction parses name, a direction a traveler on b can take. A strategy
+// pulling any other way would leave the traveler standing for good.
+func direction(name string, b *board.Board) (board.Direction, error) {
+	d, err := board.ParseDirection(name)
+	if err != nil {
+		return 0, err
+	}
+	if !slices.Contains(b.Directions(), d) {
+		return 0, fmt.Errorf("no direction %s on a %s board", d, strings.ToLower(b.Topology.String()))
+	}
+	return d, nil
+}
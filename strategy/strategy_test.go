@@ -0,0 +1,44 @@
+package strategy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dawid831/ParallelProgramming/board"
+	"github.com/dawid831/ParallelProgramming/replay"
+)
+
+func TestNewRejectsDirectionsOffTheTopology(t *testing.T) {
+	tests := []struct {
+		topology board.Topology
+		spec     string
+		err      string
+	}{
+		{board.Torus, "fixed:UP", ""},
+		{board.Torus, "biased:DOWN:2", ""},
+		{board.Torus, "fixed:UP_LEFT", "no direction UP_LEFT on a torus board"},
+		{board.Hex, "fixed:UP_LEFT", ""},
+		{board.Hex, "fixed:UP", "no direction UP on a hex board"},
+		{board.Hex, "biased:DOWN", "no direction DOWN on a hex board"},
+		{board.Torus3D, "biased:ABOVE", ""},
+		{board.Bounded, "fixed:BELOW", "no direction BELOW on a bounded board"},
+		{board.Torus, "fixed:SIDEWAYS", "unknown direction"},
+	}
+	for _, tt := range tests {
+		depth := 1
+		if tt.topology == board.Torus3D {
+			depth = 2
+		}
+		b, err := board.NewTopology(tt.topology, 4, 4, depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = New(tt.spec, b, replay.NewLog(1).Dice(0))
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("%s on %s: %v", tt.spec, tt.topology, err)
+		case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+			t.Errorf("%s on %s: error %v, want %q", tt.spec, tt.topology, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package sweep
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dawid831/ParallelProgramming/tracefmt"
+)
+
+// Metrics sum up the traces of one run.
+type Metrics struct {
+	Entities int
+	Traces   int
+	// Duration is the timestamp of the last trace.
+	Duration time.Duration
+	// Letters counts the entities with letter symbols, travelers and
+	// processes, and Lowercase those of them that ended lower case, that
+	// is travelers that gave up.
+	Letters, Lowercase int
+	// Deadlocks counts the DEADLOCK annotations.
+	Deadlocks int
+	// States are the labels of the parameter line and Entered counts, for
+	// every one of them, how often a process changed to that state.
+	States  []string
+	Entered map[string]int
+	// Values are the "KEY= value;" entries of the parameter line, like
+	// MAX_TICKET.
+	Values map[string]string
+}
+
+// Measure sums up log.
+func Measure(log *tracefmt.Log) Metrics {
+	m := Metrics{
+		Entities: log.Header.NrOfEntities,
+		Traces:   len(log.Records),
+		Entered:  make(map[string]int),
+		Values:   log.Header.Values,
+	}
+	if log.Layout == tracefmt.HeaderLast {
+		m.States = log.Header.Labels
+	}
+	for _, r := range log.Records {
+		m.Duration = max(m.Duration, r.TimeStamp)
+		// the state of a process is its Y, counted in the order of the labels
+		if r.Y >= 0 && r.Y < len(m.States) {
+			m.Entered[m.States[r.Y]]++
+		}
+	}
+	// an entity ends in its latest record, which in a log Validate would
+	// reject need not be its last line
+	for _, history := range tracefmt.Histories(log) {
+		symbol := history[len(history)-1].Symbol
+		if unicode.IsLetter(symbol) {
+			m.Letters++
+			if unicode.IsLower(symbol) {
+				m.Lowercase++
+			}
+		}
+	}
+	for _, a := range log.Annotations {
+		if a.Label == "DEADLOCK" {
+			m.Deadlocks++
+		}
+	}
+	return m
+}
+
+// LowercaseRate is the share of the letter entities that ended lower case.
+func (m Metrics) LowercaseRate() float64 {
+	if m.Letters == 0 {
+		return 0
+	}
+	return float64(m.Lowercase) / float64(m.Letters)
+}
+
+// WriteCSV writes one row per result: the swept parameters, the repetition
+// and seed, the metrics and the error, if any. Labels and values of the
+// parameter line get a column each, named in lower case, from every row
+// that has them: the states in their order, the values sorted.
+func WriteCSV(w io.Writer, params Params, results []Result) error {
+	var labelColumns []string
+	labels, values := make(map[string]bool), make(map[string]bool)
+	for _, r := range results {
+		for _, label := range r.Metrics.States {
+			if !labels[label] {
+				labels[label] = true
+				labelColumns = append(labelColumns, label)
+			}
+		}
+		for key := range r.Metrics.Values {
+			values[key] = true
+		}
+	}
+	valueColumns := make([]string, 0, len(values))
+	for key := range values {
+		valueColumns = append(valueColumns, key)
+	}
+	sort.Strings(valueColumns)
+
+	header := make([]string, 0, len(params)+9+len(labelColumns)+len(valueColumns))
+	for _, p := range params {
+		header = append(header, p.Name)
+	}
+	header = append(header, "rep", "seed", "entities", "traces", "duration_s", "letters", "lowercase", "lowercase_rate", "deadlocks")
+	for _, label := range labelColumns {
+		header = append(header, "entered_"+strings.ToLower(label))
+	}
+	for _, key := range valueColumns {
+		header = append(header, strings.ToLower(key))
+	}
+	header = append(header, "error")
+
+	out := csv.NewWriter(w)
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, r := range results {
+		m := r.Metrics
+		row := append([]string(nil), r.Point...)
+		row = append(row,
+			strconv.Itoa(r.Rep),
+			strconv.FormatInt(r.Seed, 10),
+			strconv.Itoa(m.Entities),
+			strconv.Itoa(m.Traces),
+			fmt.Sprintf("%.9f", m.Duration.Seconds()),
+			strconv.Itoa(m.Letters),
+			strconv.Itoa(m.Lowercase),
+			fmt.Sprintf("%.4f", m.LowercaseRate()),
+			strconv.Itoa(m.Deadlocks),
+		)
+		for _, label := range labelColumns {
+			row = append(row, strconv.Itoa(m.Entered[label]))
+		}
+		for _, key := range valueColumns {
+			row = append(row, m.Values[key])
+		}
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+		}
+		if err := out.Write(append(row, errText)); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
@@ -0,0 +1,177 @@
+// Package sweep runs a scenario of cmd/sim over ranges of its parameters,
+// each point several times, and measures every run from its traces. The
+// runs are processes of their own, as the scenarios keep their parameters
+// in package variables, so independent runs go in parallel.
+package sweep
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dawid831/ParallelProgramming/tracefmt"
+)
+
+// Param is a flag of the scenario and the values a sweep gives it.
+type Param struct {
+	Name   string
+	Values []string
+}
+
+// ParseParam reads name=LO..HI[:STEP], a range of integers, or
+// name=V1,V2,..., a list of values of any kind but without commas.
+func ParseParam(s string) (Param, error) {
+	name, values, ok := strings.Cut(s, "=")
+	name = strings.TrimLeft(name, "-")
+	if !ok || name == "" || values == "" {
+		return Param{}, fmt.Errorf("parameter %q: want name=lo..hi[:step] or name=v1,v2,...", s)
+	}
+	p := Param{Name: name}
+	lo, hi, isRange := strings.Cut(values, "..")
+	if !isRange {
+		p.Values = strings.Split(values, ",")
+		return p, nil
+	}
+	hi, step, hasStep := strings.Cut(hi, ":")
+	from, err1 := strconv.Atoi(lo)
+	to, err2 := strconv.Atoi(hi)
+	by := 1
+	var err3 error
+	if hasStep {
+		by, err3 = strconv.Atoi(step)
+	}
+	if err := errors.Join(err1, err2, err3); err != nil || by < 1 || to < from {
+		return Param{}, fmt.Errorf("parameter %q: want integers lo <= hi and a step of at least 1", s)
+	}
+	for v := from; v <= to; v += by {
+		p.Values = append(p.Values, strconv.Itoa(v))
+	}
+	return p, nil
+}
+
+// Params is the repeatable -vary command line flag.
+type Params []Param
+
+func (p *Params) String() string {
+	if p == nil {
+		return ""
+	}
+	s := make([]string, len(*p))
+	for i, param := range *p {
+		s[i] = param.Name + "=" + strings.Join(param.Values, ",")
+	}
+	return strings.Join(s, " ")
+}
+
+func (p *Params) Set(s string) error {
+	param, err := ParseParam(s)
+	if err != nil {
+		return err
+	}
+	*p = append(*p, param)
+	return nil
+}
+
+// Run is one run of a sweep, a row of its output.
+type Run struct {
+	Row int
+	// Point holds the value of every Param, in their order.
+	Point []string
+	Rep   int
+	Seed  int64
+}
+
+// Runs lists the runs of a sweep: every combination of the values of
+// params, the first param changing slowest, reps times each. Row i is
+// seeded with seed+i, so any row can be run again on its own.
+func Runs(params Params, reps int, seed int64) []Run {
+	points := [][]string{nil}
+	for _, p := range params {
+		var next [][]string
+		for _, point := range points {
+			for _, v := range p.Values {
+				next = append(next, append(point[:len(point):len(point)], v))
+			}
+		}
+		points = next
+	}
+	var runs []Run
+	for _, point := range points {
+		for rep := 0; rep < reps; rep++ {
+			runs = append(runs, Run{Row: len(runs), Point: point, Rep: rep, Seed: seed + int64(len(runs))})
+		}
+	}
+	return runs
+}
+
+// Args are the flags run adds to those of the scenario.
+func (r Run) Args(params Params) []string {
+	args := make([]string, 0, len(params)+1)
+	for i, p := range params {
+		args = append(args, "-"+p.Name+"="+r.Point[i])
+	}
+	return append(args, "-seed="+strconv.FormatInt(r.Seed, 10))
+}
+
+// Result is a finished run. Err is set when the run failed or its traces
+// could not be read; Metrics are still whatever the traces gave.
+type Result struct {
+	Run
+	Metrics Metrics
+	Err     error
+}
+
+// Sweep runs command, a scenario of sim with its flags, once per run with
+// the flags of the run added, at most parallel at a time. The traces are
+// measured and dropped, nothing is printed. Results are in row order.
+func Sweep(ctx context.Context, command []string, params Params, runs []Run, parallel int) []Result {
+	results := make([]Result, len(runs))
+	rows := make(chan int)
+	var wg sync.WaitGroup
+	for range max(parallel, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range rows {
+				results[row] = measure(ctx, command, params, runs[row])
+			}
+		}()
+	}
+	for row := range runs {
+		rows <- row
+	}
+	close(rows)
+	wg.Wait()
+	return results
+}
+
+// measure runs r and reads its traces.
+func measure(ctx context.Context, command []string, params Params, r Run) Result {
+	cmd := exec.CommandContext(ctx, command[0], append(command[1:len(command):len(command)], r.Args(params)...)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	result := Result{Run: r}
+	runErr := cmd.Run()
+	if runErr != nil {
+		// the last line of stderr says why, the seed line comes first
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		if last := lines[len(lines)-1]; last != "" {
+			runErr = fmt.Errorf("%v: %s", runErr, last)
+		}
+	}
+	if runErr != nil && stdout.Len() == 0 {
+		result.Err = runErr
+		return result
+	}
+	log, err := tracefmt.Parse(&stdout)
+	if err == nil {
+		result.Metrics = Measure(log)
+	}
+	result.Err = errors.Join(runErr, err)
+	return result
+}
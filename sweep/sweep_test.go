@@ -0,0 +1,95 @@
+package sweep
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dawid831/ParallelProgramming/tracefmt"
+)
+
+func TestRunsCoverTheGridWithASeedPerRow(t *testing.T) {
+	var params Params
+	for _, s := range []string{"travelers=2..6:2", "-strategy=random,fixed:UP"} {
+		if err := params.Set(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runs := Runs(params, 2, 10)
+	if len(runs) != 3*2*2 {
+		t.Fatalf("%d runs, want 12", len(runs))
+	}
+	for i, r := range runs {
+		if r.Row != i || r.Seed != 10+int64(i) {
+			t.Errorf("run %d is row %d with seed %d", i, r.Row, r.Seed)
+		}
+	}
+	if got, want := runs[5].Args(params), []string{"-travelers=4", "-strategy=random", "-seed=15"}; !slices.Equal(got, want) {
+		t.Errorf("row 5 runs with %v, want %v", got, want)
+	}
+	for _, bad := range []string{"travelers", "travelers=5..2", "travelers=1..5:0", "=1,2"} {
+		if _, err := ParseParam(bad); err == nil {
+			t.Errorf("ParseParam(%q) accepted", bad)
+		}
+	}
+}
+
+func TestMeasureProcessTraces(t *testing.T) {
+	log, err := tracefmt.Parse(strings.NewReader(`0.1 0 0 0 A
+0.2 0 0 1 A
+0.3 1 1 1 B
+0.4 0 0 0 A
+-1 2 2 2 LOCAL_SECTION;ENTRY_PROTOCOL;MAX_TICKET= 3;
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Measure(log)
+	if m.Entered["LOCAL_SECTION"] != 2 || m.Entered["ENTRY_PROTOCOL"] != 2 || m.Letters != 2 || m.Lowercase != 0 {
+		t.Errorf("measured %+v", m)
+	}
+	var out bytes.Buffer
+	if err := WriteCSV(&out, nil, []Result{{Metrics: m}}); err != nil {
+		t.Fatal(err)
+	}
+	want := "rep,seed,entities,traces,duration_s,letters,lowercase,lowercase_rate,deadlocks,entered_local_section,entered_entry_protocol,max_ticket,error\n" +
+		"0,0,2,4,0.400000000,2,0,0.0000,0,2,2,3,\n"
+	if out.String() != want {
+		t.Errorf("CSV\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestMeasureLowercaseTravelers(t *testing.T) {
+	log, err := tracefmt.Parse(strings.NewReader(`-1 3 4 4 TOPOLOGY= TORUS;
+0.0 0 0 0 A
+0.0 1 1 1 B
+0.0 2 2 2 0
+0.5 1 1 2 b
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := Measure(log); m.Letters != 2 || m.Lowercase != 1 || m.LowercaseRate() != 0.5 || len(m.Entered) != 0 {
+		t.Errorf("measured %+v", m)
+	}
+}
+
+func TestMeasureTakesTheLatestRecordOfEachEntity(t *testing.T) {
+	// out of time order: B gave up at 0.5 but its line from 0.2 comes last
+	log, err := tracefmt.Parse(strings.NewReader(`-1 3 4 4 TOPOLOGY= TORUS;
+0.0 0 0 0 A
+0.5 1 1 2 b
+0.9 0 0 1 a
+0.3 0 1 0 A
+0.0 1 1 0 B
+0.2 1 1 1 B
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := Measure(log); m.Letters != 2 || m.Lowercase != 2 || m.Duration != 900*time.Millisecond {
+		t.Errorf("measured %+v", m)
+	}
+}
@@ -0,0 +1,261 @@
+// Package tracefmt reads and validates the text protocol written by every
+// simulation: "timestamp id x y symbol" trace lines plus one
+// "-1 N W H [LABEL;...]" parameter line, either first (travelers) or last
+// (lista3/lista4 printers). Lines starting with "-2" are annotations for the
+// display, e.g. "-2 timestamp DEADLOCK payload".
+package tracefmt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout tells where the parameter line was found.
+type Layout int
+
+const (
+	HeaderFirst Layout = iota
+	HeaderLast
+)
+
+func (l Layout) String() string {
+	return [...]string{"HEADER_FIRST", "HEADER_LAST"}[l]
+}
+
+// Header is the "-1 N W H [LABEL;...]" parameter line.
+type Header struct {
+	NrOfEntities int
+	Width        int
+	Height       int
+	// Labels are the plain ";"-terminated entries, e.g. LOCAL_SECTION or
+	// EXTRA_LABEL.
+	Labels []string
+	// Values are the "KEY= value;" entries, e.g. MAX_TICKET.
+	Values map[string]string
+	Line   int
+}
+
+// Topology is the board topology named by "TOPOLOGY= T;". Headers without
+// one come from the original torus programs.
+func (h Header) Topology() string {
+	if t, ok := h.Values["TOPOLOGY"]; ok {
+		return t
+	}
+	return "TORUS"
+}
+
+// Record is a single trace line.
+type Record struct {
+	TimeStamp time.Duration
+	ID        int
+	X, Y      int
+	Symbol    rune
+	Line      int
+}
+
+// OffBoard reports whether the record uses the (-1, -1) position the
+// simulations write for entities that left the board.
+func (r Record) OffBoard() bool {
+	return r.X == -1 && r.Y == -1
+}
+
+// Annotation is a "-2 timestamp LABEL payload" line, e.g. a deadlock the
+// display should highlight.
+type Annotation struct {
+	TimeStamp time.Duration
+	Label     string
+	Payload   string
+	Line      int
+}
+
+type Log struct {
+	Header      Header
+	Layout      Layout
+	Records     []Record
+	Annotations []Annotation
+}
+
+// Error points at the offending line of the input.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Line == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("line %d: %s", e.Line, e.Msg)
+}
+
+func errorf(line int, format string, args ...interface{}) *Error {
+	return &Error{Line: line, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Parse reads a whole trace stream. It only checks syntax and header
+// placement; use Validate for the semantic checks.
+func Parse(r io.Reader) (*Log, error) {
+	log := &Log{}
+	headerSeen := false
+	lineNr := 0
+	lastNonEmpty := 0
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNr++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		lastNonEmpty = lineNr
+		if IsAnnotation(line) {
+			annotation, err := ParseAnnotation(line)
+			if err != nil {
+				return nil, errorf(lineNr, "%v", err)
+			}
+			annotation.Line = lineNr
+			log.Annotations = append(log.Annotations, annotation)
+			continue
+		}
+		if headerSeen && log.Layout == HeaderLast {
+			return nil, errorf(lineNr, "trace line after the parameter line at line %d", log.Header.Line)
+		}
+
+		if IsHeader(line) {
+			if headerSeen {
+				return nil, errorf(lineNr, "second parameter line, first one at line %d", log.Header.Line)
+			}
+			header, err := ParseHeader(line)
+			if err != nil {
+				return nil, errorf(lineNr, "%v", err)
+			}
+			header.Line = lineNr
+			log.Header = header
+			headerSeen = true
+			if len(log.Records) == 0 {
+				log.Layout = HeaderFirst
+			} else {
+				log.Layout = HeaderLast
+			}
+			continue
+		}
+
+		record, err := ParseRecord(line)
+		if err != nil {
+			return nil, errorf(lineNr, "%v", err)
+		}
+		record.Line = lineNr
+		log.Records = append(log.Records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if !headerSeen {
+		return nil, errorf(lastNonEmpty, "missing \"-1 N W H\" parameter line")
+	}
+	return log, nil
+}
+
+// IsHeader reports whether line is a parameter line.
+func IsHeader(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) > 0 && fields[0] == "-1"
+}
+
+// ParseHeader parses "-1 N W H [LABEL;...]".
+func ParseHeader(line string) (Header, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 || fields[0] != "-1" {
+		return Header{}, fmt.Errorf("parameter line %q: want \"-1 N W H [LABEL;...]\"", line)
+	}
+
+	var header Header
+	numbers := []*int{&header.NrOfEntities, &header.Width, &header.Height}
+	names := []string{"N", "W", "H"}
+	for i, dst := range numbers {
+		n, err := strconv.Atoi(fields[i+1])
+		if err != nil || n < 0 {
+			return Header{}, fmt.Errorf("parameter line: %s=%q is not a non-negative integer", names[i], fields[i+1])
+		}
+		*dst = n
+	}
+
+	// Labels may contain spaces after "KEY=", so split the rest on ';'.
+	rest := strings.Join(fields[4:], " ")
+	for _, entry := range strings.Split(rest, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if key, value, ok := strings.Cut(entry, "="); ok {
+			if header.Values == nil {
+				header.Values = make(map[string]string)
+			}
+			header.Values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			continue
+		}
+		header.Labels = append(header.Labels, entry)
+	}
+	return header, nil
+}
+
+// IsAnnotation reports whether line is an annotation line.
+func IsAnnotation(line string) bool {
+	fields := strings.Fields(line)
+	return len(fields) > 0 && fields[0] == "-2"
+}
+
+// ParseAnnotation parses "-2 timestamp LABEL [payload]".
+func ParseAnnotation(line string) (Annotation, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || fields[0] != "-2" {
+		return Annotation{}, fmt.Errorf("annotation line %q: want \"-2 timestamp LABEL [payload]\"", line)
+	}
+	seconds, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return Annotation{}, fmt.Errorf("annotation line: timestamp %q is not a number", fields[1])
+	}
+	return Annotation{
+		TimeStamp: time.Duration(seconds * float64(time.Second)),
+		Label:     fields[2],
+		Payload:   strings.Join(fields[3:], " "),
+	}, nil
+}
+
+// ParseRecord parses "timestamp id x y symbol".
+func ParseRecord(line string) (Record, error) {
+	fields := strings.Fields(line)
+	if len(fields) != 5 {
+		return Record{}, fmt.Errorf("trace line %q: want 5 fields \"timestamp id x y symbol\", got %d", line, len(fields))
+	}
+
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return Record{}, fmt.Errorf("trace line: timestamp %q is not a number", fields[0])
+	}
+	var ints [3]int
+	names := []string{"id", "x", "y"}
+	for i := range ints {
+		ints[i], err = strconv.Atoi(fields[i+1])
+		if err != nil {
+			return Record{}, fmt.Errorf("trace line: %s %q is not an integer", names[i], fields[i+1])
+		}
+	}
+	symbol := []rune(fields[4])
+	if len(symbol) != 1 {
+		return Record{}, fmt.Errorf("trace line: symbol %q is not a single character", fields[4])
+	}
+
+	return Record{
+		TimeStamp: time.Duration(seconds * float64(time.Second)),
+		ID:        ints[0],
+		X:         ints[1],
+		Y:         ints[2],
+		Symbol:    symbol[0],
+	}, nil
+}
@@ -0,0 +1,124 @@
+package tracefmt
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		layout  Layout
+		records int
+		err     string
+	}{
+		{"header first", "-1 2 4 4 TOPOLOGY= TORUS;\n0.0 0 1 1 A\n0.5 1 2 2 B\n", HeaderFirst, 2, ""},
+		{"header last", "0.0 0 0 0 A\n0.1 0 0 1 A\n-1 1 1 2 LOCAL_SECTION;CRITICAL_SECTION;\n", HeaderLast, 2, ""},
+		{"annotations anywhere", "-1 1 2 2\n-2 0.1 FILL 0 0 1 2\n0.1 0 0 0 A\n-2 0.2 DEADLOCK 0 0 0;\n", HeaderFirst, 1, ""},
+		{"blank lines", "\n-1 1 2 2\n\n0.0 0 0 0 A\n\n", HeaderFirst, 1, ""},
+		{"no header", "0.0 0 0 0 A\n", 0, 0, "line 1: missing"},
+		{"two headers", "-1 1 2 2\n-1 1 2 2\n", 0, 0, "line 2: second parameter line"},
+		{"trace after last header", "0.0 0 0 0 A\n-1 1 1 1 X;\n0.1 0 0 0 A\n", 0, 0, "line 3: trace line after"},
+		{"short header", "-1 1 2\n", 0, 0, "line 1: parameter line"},
+		{"negative header", "-1 -3 2 2\n", 0, 0, "N=\"-3\""},
+		{"four fields", "-1 1 2 2\n0.0 0 0 0\n", 0, 0, "line 2: trace line"},
+		{"bad timestamp", "-1 1 2 2\nnow 0 0 0 A\n", 0, 0, "timestamp \"now\""},
+		{"bad id", "-1 1 2 2\n0.0 a 0 0 A\n", 0, 0, "id \"a\""},
+		{"long symbol", "-1 1 2 2\n0.0 0 0 0 AB\n", 0, 0, "symbol \"AB\""},
+		{"short annotation", "-1 1 2 2\n-2 0.1\n", 0, 0, "line 2: annotation line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := Parse(strings.NewReader(tt.input))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if log.Layout != tt.layout || len(log.Records) != tt.records {
+				t.Errorf("layout %v with %d records, want %v with %d", log.Layout, len(log.Records), tt.layout, tt.records)
+			}
+		})
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	log, err := Parse(strings.NewReader("-1 2 5 3 LOCAL_SECTION;MAX_TICKET= 8;\n-2 1.5 DEADLOCK 0 1 1;1 2 2\n0.25 1 4 2 b\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := log.Header
+	if h.NrOfEntities != 2 || h.Width != 5 || h.Height != 3 || !slices.Equal(h.Labels, []string{"LOCAL_SECTION"}) ||
+		h.Values["MAX_TICKET"] != "8" || h.Topology() != "TORUS" {
+		t.Errorf("header %+v", h)
+	}
+	a := log.Annotations[0]
+	if a.TimeStamp != 1500*time.Millisecond || a.Label != "DEADLOCK" || a.Payload != "0 1 1;1 2 2" || a.Line != 2 {
+		t.Errorf("annotation %+v", a)
+	}
+	r := log.Records[0]
+	if r.TimeStamp != 250*time.Millisecond || r.ID != 1 || r.X != 4 || r.Y != 2 || r.Symbol != 'b' || r.Line != 3 {
+		t.Errorf("record %+v", r)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		errs  []string
+	}{
+		{"clean", "-1 2 3 3\n0.0 0 0 0 A\n0.0 1 2 2 B\n0.1 0 -1 -1 a\n", nil},
+		// other IDs may come in between, one ID must not go back in time
+		{"interleaved", "-1 2 3 3\n0.2 0 1 0 A\n0.0 1 2 2 B\n0.3 0 1 1 A\n0.1 1 2 1 B\n", nil},
+		{"going back", "-1 2 3 3\n0.2 0 1 0 A\n0.3 1 1 1 B\n0.1 0 0 0 A\n0.0 1 2 2 B\n", []string{
+			"line 4: id 0 timestamp 0.100000000 goes back from 0.200000000 at line 2",
+			"line 5: id 1 timestamp 0.000000000 goes back from 0.300000000 at line 3",
+		}},
+		{"off the board", "-1 1 3 3\n0.0 0 3 0 A\n", []string{"line 2: id 0 at (3, 0) outside 3x3 board"}},
+		{"unknown id", "-1 1 3 3\n0.0 0 0 0 A\n0.0 1 0 0 B\n", []string{
+			"line 3: unknown id 1",
+			"line 1: header declares 1 entities, traces use 2 distinct ids [0 1]",
+		}},
+		{"missing id", "-1 2 3 3\n0.0 0 0 0 A\n", []string{"line 1: header declares 2 entities, traces use 1 distinct ids [0]"}},
+		{"negative timestamp", "-1 1 3 3\n-0.5 0 0 0 A\n", []string{"line 2: id 0 timestamp -0.500000000 is before the start"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, err := Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			errs := Validate(log)
+			if len(errs) != len(tt.errs) {
+				t.Fatalf("errors %v, want %d", errs, len(tt.errs))
+			}
+			for i, err := range errs {
+				if !strings.HasPrefix(err.Error(), tt.errs[i]) {
+					t.Errorf("error %q, want %q", err, tt.errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHistoriesAreInTimeOrder(t *testing.T) {
+	log, err := Parse(strings.NewReader("-1 1 3 3\n0.2 0 2 0 A\n0.1 0 1 0 A\n0.2 0 2 1 a\n0.0 0 0 0 A\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var xs, ys []int
+	for _, r := range Histories(log)[0] {
+		xs, ys = append(xs, r.X), append(ys, r.Y)
+	}
+	if !slices.Equal(xs, []int{0, 1, 2, 2}) || !slices.Equal(ys, []int{0, 0, 0, 1}) {
+		t.Errorf("history at x %v, y %v", xs, ys)
+	}
+}
@@ -0,0 +1,80 @@
+package tracefmt
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Histories returns the records of every ID in time order, also for a log
+// Validate rejects for going backwards. Records with equal timestamps keep
+// their order in the input.
+func Histories(log *Log) map[int][]Record {
+	histories := make(map[int][]Record)
+	for _, r := range log.Records {
+		histories[r.ID] = append(histories[r.ID], r)
+	}
+	for _, h := range histories {
+		sort.SliceStable(h, func(i, j int) bool {
+			return h[i].TimeStamp < h[j].TimeStamp
+		})
+	}
+	return histories
+}
+
+// Validate checks the records against the header and returns every problem
+// found, in input order:
+//   - coordinates outside 0..W-1 x 0..H-1 (other than the (-1, -1) exit),
+//   - IDs outside 0..N-1,
+//   - negative timestamps,
+//   - timestamps going backwards for one ID,
+//   - a number of distinct IDs different from N.
+func Validate(log *Log) []error {
+	var errs []error
+	header := log.Header
+	lastStamp := make(map[int]Record)
+
+	for _, r := range log.Records {
+		if r.ID < 0 || r.ID >= header.NrOfEntities {
+			errs = append(errs, errorf(r.Line, "unknown id %d, header declares ids 0..%d", r.ID, header.NrOfEntities-1))
+		}
+		if !r.OffBoard() && (r.X < 0 || r.X >= header.Width || r.Y < 0 || r.Y >= header.Height) {
+			errs = append(errs, errorf(r.Line, "id %d at (%d, %d) outside %dx%d board", r.ID, r.X, r.Y, header.Width, header.Height))
+		}
+		if r.TimeStamp < 0 {
+			errs = append(errs, errorf(r.Line, "id %d timestamp %.9f is before the start", r.ID, r.TimeStamp.Seconds()))
+		}
+		if prev, ok := lastStamp[r.ID]; ok && r.TimeStamp < prev.TimeStamp {
+			errs = append(errs, errorf(r.Line, "id %d timestamp %.9f goes back from %.9f at line %d",
+				r.ID, r.TimeStamp.Seconds(), prev.TimeStamp.Seconds(), prev.Line))
+		}
+		lastStamp[r.ID] = r
+	}
+
+	if len(lastStamp) != header.NrOfEntities {
+		ids := make([]int, 0, len(lastStamp))
+		for id := range lastStamp {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		errs = append(errs, errorf(header.Line, "header declares %d entities, traces use %d distinct ids %v",
+			header.NrOfEntities, len(ids), ids))
+	}
+	return errs
+}
+
+// ReadAndValidate parses r and validates it in one go. The error is the
+// parse error, or the first validation error with a count of the rest.
+func ReadAndValidate(r io.Reader) (*Log, error) {
+	log, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+	if errs := Validate(log); len(errs) > 0 {
+		if len(errs) == 1 {
+			return log, errs[0]
+		}
+		return log, fmt.Errorf("%v (and %d more)", errs[0], len(errs)-1)
+	}
+	return log, nil
+}